@@ -0,0 +1,205 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// Cache is an on-disk cache of fetched documents. It stores the ETag and
+// Last-Modified validators of a response along with its body, and revalidates
+// them with a conditional request on the next fetch of the same URL. The
+// microdata is parsed from the cached body with the options of each fetch.
+type Cache struct {
+	// Client is the HTTP client used for requests. The default is
+	// http.DefaultClient.
+	Client *http.Client
+
+	dir string
+
+	requests, hits, misses, errors int64
+}
+
+// CacheStats holds the statistics of a cache. Hits counts the documents served
+// from the cache after a 304 Not Modified response, Misses counts the
+// documents fetched and parsed and Errors counts the failed requests.
+type CacheStats struct {
+	Requests int64 `json:"requests"`
+	Hits     int64 `json:"hits"`
+	Misses   int64 `json:"misses"`
+	Errors   int64 `json:"errors"`
+}
+
+// cacheEntry is the on-disk representation of a cached document.
+type cacheEntry struct {
+	URL             string    `json:"url"`
+	ETag            string    `json:"etag,omitempty"`
+	LastModified    string    `json:"last_modified,omitempty"`
+	ContentType     string    `json:"content_type,omitempty"`
+	ContentLanguage string    `json:"content_language,omitempty"`
+	Fetched         time.Time `json:"fetched"`
+	Body            []byte    `json:"body"`
+}
+
+// NewCache returns a cache storing its entries in the given directory, which
+// is created when it doesn't exist.
+func NewCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Stats returns the statistics of the cache.
+func (c *Cache) Stats() CacheStats {
+	return CacheStats{
+		Requests: atomic.LoadInt64(&c.requests),
+		Hits:     atomic.LoadInt64(&c.hits),
+		Misses:   atomic.LoadInt64(&c.misses),
+		Errors:   atomic.LoadInt64(&c.errors),
+	}
+}
+
+// ParseURL is like the ParseURL function, but parses the cached document when
+// the server responds to the conditional request with 304 Not Modified.
+// Responses with a validator are added to the cache. The options apply to
+// cached documents too, as they're parsed again on every fetch.
+func (c *Cache) ParseURL(urlStr string, opts ...Option) (*Microdata, error) {
+	atomic.AddInt64(&c.requests, 1)
+	data, err := c.parseURL(urlStr, opts)
+	if err != nil {
+		atomic.AddInt64(&c.errors, 1)
+	}
+	return data, err
+}
+
+func (c *Cache) parseURL(urlStr string, opts []Option) (*Microdata, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := c.load(urlStr)
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		data, err := parseCached(entry.Body, entry.ContentType, entry.ContentLanguage, u, opts)
+		if err != nil {
+			return nil, err
+		}
+		atomic.AddInt64(&c.hits, 1)
+		return data, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	contentType, contentLanguage := resp.Header.Get("Content-Type"), resp.Header.Get("Content-Language")
+	data, err := parseCached(body, contentType, contentLanguage, u, opts)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt64(&c.misses, 1)
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if resp.StatusCode == http.StatusOK && (etag != "" || lastModified != "") {
+		c.store(&cacheEntry{
+			URL:             urlStr,
+			ETag:            etag,
+			LastModified:    lastModified,
+			ContentType:     contentType,
+			ContentLanguage: contentLanguage,
+			Fetched:         time.Now().UTC(),
+			Body:            body,
+		})
+	}
+	return data, nil
+}
+
+// parseCached parses the body of a response with the given Content-Type and
+// Content-Language headers, like ParseURL.
+func parseCached(body []byte, contentType, contentLanguage string, u *url.URL, opts []Option) (*Microdata, error) {
+	opts = append([]Option{WithLanguage(contentLanguage)}, opts...)
+	p, err := newParser(bytes.NewReader(body), contentType, u, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return p.parse()
+}
+
+// path returns the path of the entry of the URL.
+func (c *Cache) path(urlStr string) string {
+	sum := sha256.Sum256([]byte(urlStr))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load returns the entry of the URL, or nil when it's missing or unreadable.
+func (c *Cache) load(urlStr string) *cacheEntry {
+	b, err := os.ReadFile(c.path(urlStr))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil || entry.URL != urlStr || entry.Body == nil {
+		return nil
+	}
+	return &entry
+}
+
+// store writes the entry atomically. Failing to store an entry only costs a
+// full request on the next fetch, so errors are ignored.
+func (c *Cache) store(entry *cacheEntry) {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	f, err := os.CreateTemp(c.dir, ".entry-*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	if err := os.Rename(f.Name(), c.path(entry.URL)); err != nil {
+		os.Remove(f.Name())
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://example.com/Person">
+			<p>My name is <span itemprop="name">Penelope</span>.</p>
+		</div>`
+
+	conditional := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/etag":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				conditional++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+		case "/last-modified":
+			if r.Header.Get("If-Modified-Since") == "Wed, 21 Oct 2015 07:28:00 GMT" {
+				conditional++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		}
+		io.WriteString(w, html)
+	}))
+	defer ts.Close()
+
+	c, err := NewCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/etag", "/last-modified", "/uncached"} {
+		first, err := c.ParseURL(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := c.ParseURL(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(first.Canonical(), second.Canonical()) {
+			t.Errorf("%s: Result should have been \"%s\", but it was \"%s\"", path, first.Canonical(), second.Canonical())
+		}
+	}
+
+	if conditional != 2 {
+		t.Errorf("Result should have been 2 conditional requests, but it was %d", conditional)
+	}
+
+	result := c.Stats()
+	expected := CacheStats{Requests: 6, Hits: 2, Misses: 4}
+	if result != expected {
+		t.Errorf("Result should have been \"%+v\", but it was \"%+v\"", expected, result)
+	}
+}
+
+func TestCacheError(t *testing.T) {
+	c, err := NewCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.ParseURL("http://127.0.0.1:0/"); err == nil {
+		t.Error("Result should have been an error, but it was nil")
+	}
+	if result := c.Stats().Errors; result != 1 {
+		t.Errorf("Result should have been 1 error, but it was %d", result)
+	}
+}
+
+func TestCacheOptions(t *testing.T) {
+	html := `<html><head><meta charset="windows-1252"></head><body>
+		<div itemscope itemtype="http://schema.org/Product"><span itemprop="name">  Anvil  </span></div>
+		<div itemscope itemtype="http://schema.org/Offer"><span itemprop="price">9.99</span></div>
+	</body></html>`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Language", "en")
+		io.WriteString(w, html)
+	}))
+	defer ts.Close()
+
+	c, err := NewCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ParseURL(ts.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := c.ParseURL(ts.URL, WithTypeFilter(&TypeFilter{Types: []string{"Product"}}), WithNormalization(TrimSpace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := c.Stats().Hits; result != 1 {
+		t.Fatalf("Result should have been 1 hit, but it was %d", result)
+	}
+	if len(data.Items) != 1 {
+		t.Fatalf("Result should have been 1 item, but it was %d", len(data.Items))
+	}
+	if result := data.Items[0].Values("name")[0]; result.Value != "Anvil" || result.Lang != "en" {
+		t.Errorf("Result should have been \"Anvil\" in en, but it was \"%v\" in %q", result.Value, result.Lang)
+	}
+	if data.Encoding == nil || data.Encoding.Name != "windows-1252" {
+		t.Errorf("Result should have been the windows-1252 encoding, but it was %v", data.Encoding)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// PropertyNames returns the property names of the item in document order: the
+// tree order of the first element of each property, including the elements
+// read through itemref. Properties which were not added by the parser, e.g. when the item was
+// decoded from JSON or built by hand, follow in lexical order.
+func (i *Item) PropertyNames() []string {
+	names := make([]string, 0, len(i.Properties))
+	seen := make(map[string]bool, len(i.Properties))
+	for _, name := range i.order {
+		if _, ok := i.Properties[name]; ok && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+
+	var rest []string
+	for name := range i.Properties {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
+
+// canonicalItem is the canonical representation of an item. Property names
+// are sorted by encoding/json, the types are sorted and deduplicated because
+// the itemtype attribute is an unordered set. The order of property values is
+// significant and kept as is.
+type canonicalItem struct {
+	Types      []string                 `json:"type"`
+	Properties map[string][]interface{} `json:"properties"`
+	ID         string                   `json:"id,omitempty"`
+}
+
+// canonical returns the canonical representation of the item.
+func (i *Item) canonical() *canonicalItem {
+	c := &canonicalItem{
+		Types:      canonicalTypes(i.Types),
+		Properties: make(map[string][]interface{}, len(i.Properties)),
+		ID:         i.ID,
+	}
+	for name, values := range i.Properties {
+		list := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			if item, ok := v.(*Item); ok {
+				list = append(list, item.canonical())
+				continue
+			}
+			list = append(list, v)
+		}
+		c.Properties[name] = list
+	}
+	return c
+}
+
+// Canonical returns the canonical JSON encoding of the item. Two items with
+// the same canonical encoding are equal, regardless of the document order of
+// their properties or types, which makes the encoding suitable for hashing.
+func (i *Item) Canonical() []byte {
+	b, err := json.Marshal(i.canonical())
+	if err != nil {
+		// Items only hold strings and items which always encode.
+		panic(err)
+	}
+	return b
+}
+
+// Canonical returns the canonical JSON encoding of the microdata. The items
+// keep their document order.
+func (m *Microdata) Canonical() []byte {
+	items := make([]*canonicalItem, 0, len(m.Items))
+	for _, item := range m.Items {
+		items = append(items, item.canonical())
+	}
+	b, err := json.Marshal(map[string]interface{}{"items": items})
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Equal reports whether the item and the other item have the same canonical
+// encoding.
+func (i *Item) Equal(other *Item) bool {
+	if i == nil || other == nil {
+		return i == other
+	}
+	return bytes.Equal(i.Canonical(), other.Canonical())
+}
+
+// Difference describes a single difference between two items. Path locates
+// the difference, e.g. "type", "id", "name[0]" or "offers[0].price[0]". Old
+// or New is nil when the value is missing on that side.
+type Difference struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// String returns a one-line description of the difference.
+func (d Difference) String() string {
+	switch {
+	case d.Old == nil:
+		return fmt.Sprintf("%s: added %s", d.Path, formatValue(d.New))
+	case d.New == nil:
+		return fmt.Sprintf("%s: removed %s", d.Path, formatValue(d.Old))
+	}
+	return fmt.Sprintf("%s: %s -> %s", d.Path, formatValue(d.Old), formatValue(d.New))
+}
+
+// Diff returns the differences between the item and the other item, in the
+// document order of the item's properties followed by the properties only
+// found on the other item. Nested items are compared recursively.
+func (i *Item) Diff(other *Item) []Difference {
+	return diffItems("", i, other)
+}
+
+// diffItems returns the differences between the items a and b, prefixing each
+// path with the given prefix.
+func diffItems(prefix string, a, b *Item) []Difference {
+	var diffs []Difference
+
+	if ta, tb := canonicalTypes(a.Types), canonicalTypes(b.Types); !reflect.DeepEqual(ta, tb) {
+		diffs = append(diffs, Difference{Path: prefix + "type", Old: ta, New: tb})
+	}
+	if a.ID != b.ID {
+		d := Difference{Path: prefix + "id"}
+		if a.ID != "" {
+			d.Old = a.ID
+		}
+		if b.ID != "" {
+			d.New = b.ID
+		}
+		diffs = append(diffs, d)
+	}
+
+	names := a.PropertyNames()
+	for _, name := range b.PropertyNames() {
+		if _, ok := a.Properties[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		va, vb := a.Properties[name], b.Properties[name]
+		n := len(va)
+		if len(vb) > n {
+			n = len(vb)
+		}
+		for k := 0; k < n; k++ {
+			path := fmt.Sprintf("%s%s[%d]", prefix, name, k)
+			var x, y interface{}
+			if k < len(va) {
+				x = va[k]
+			}
+			if k < len(vb) {
+				y = vb[k]
+			}
+
+			ix, xok := x.(*Item)
+			iy, yok := y.(*Item)
+			switch {
+			case xok && yok:
+				diffs = append(diffs, diffItems(path+".", ix, iy)...)
+			case xok || yok:
+				diffs = append(diffs, Difference{Path: path, Old: x, New: y})
+			case !reflect.DeepEqual(x, y):
+				diffs = append(diffs, Difference{Path: path, Old: x, New: y})
+			}
+		}
+	}
+	return diffs
+}
+
+// canonicalTypes returns a sorted copy of the given types without duplicates.
+func canonicalTypes(types []string) []string {
+	c := make([]string, 0, len(types))
+	c = append(c, types...)
+	sort.Strings(c)
+
+	j := 0
+	for k, t := range c {
+		if k > 0 && t == c[j-1] {
+			continue
+		}
+		c[j] = t
+		j++
+	}
+	return c[:j]
+}
+
+// formatValue returns a short representation of a property value.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string, []string:
+		return fmt.Sprintf("%q", v)
+	case *Item:
+		return fmt.Sprintf("item%v", v.Types)
+	}
+	return fmt.Sprint(v)
+}
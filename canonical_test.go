@@ -0,0 +1,111 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPropertyNames(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://example.com/Person">
+			<span itemprop="name">Penelope</span>
+			<span itemprop="age">22</span>
+			<span itemprop="birthPlace">Amsterdam</span>
+			<span itemprop="name">Penny</span>
+		</div>`
+
+	data := ParseData(html, t)
+
+	result := data.Items[0].PropertyNames()
+	expected := []string{"name", "age", "birthPlace"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", expected, result)
+	}
+}
+
+func TestPropertyNamesWithoutOrder(t *testing.T) {
+	item := NewItem()
+	item.Properties["name"] = ValueList{"Penelope"}
+	item.Properties["age"] = ValueList{"22"}
+
+	result := item.PropertyNames()
+	expected := []string{"age", "name"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", expected, result)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	a := ParseData(`
+		<div itemscope itemtype="http://example.com/Person http://example.com/Author">
+			<span itemprop="name">Penelope</span>
+			<span itemprop="age">22</span>
+		</div>`, t)
+	b := ParseData(`
+		<div itemscope itemtype="http://example.com/Author http://example.com/Person">
+			<span itemprop="age">22</span>
+			<span itemprop="name">Penelope</span>
+		</div>`, t)
+
+	result := string(a.Items[0].Canonical())
+	expected := `{"type":["http://example.com/Author","http://example.com/Person"],"properties":{"age":["22"],"name":["Penelope"]}}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+
+	if !a.Items[0].Equal(b.Items[0]) {
+		t.Errorf("Items should have been equal, but they were not: \"%s\" and \"%s\"", a.Items[0].Canonical(), b.Items[0].Canonical())
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := ParseData(blogSnippet, t)
+	b := ParseData(blogSnippet, t)
+	if !a.Items[0].Equal(b.Items[0]) {
+		t.Error("Items should have been equal, but they were not")
+	}
+
+	b.Items[0].Properties["comment"][1].(*Item).Properties["commentTime"][0] = "2013-08-30"
+	if a.Items[0].Equal(b.Items[0]) {
+		t.Error("Items should not have been equal, but they were")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := ParseData(blogSnippet, t)
+	b := ParseData(blogSnippet, t)
+
+	item := b.Items[0]
+	item.Types = []string{"http://schema.org/Article"}
+	item.ID = "urn:example:1"
+	item.Properties["headline"][0] = "Final report"
+	item.Properties["comment"][1].(*Item).Properties["creator"][0].(*Item).Properties["name"][0] = "Charlie"
+	delete(item.Properties, "datePublished")
+	item.Properties["author"] = ValueList{"Greg"}
+
+	var testTable = []string{
+		`type: ["http://schema.org/BlogPosting"] -> ["http://schema.org/Article"]`,
+		`id: added "urn:example:1"`,
+		`headline[0]: "Progress report" -> "Final report"`,
+		`datePublished[0]: removed "2013-08-29"`,
+		`comment[1].creator[0].name[0]: "Charlotte" -> "Charlie"`,
+		`author[0]: added "Greg"`,
+	}
+
+	diffs := a.Items[0].Diff(item)
+	if len(diffs) != len(testTable) {
+		t.Fatalf("Result should have been %d differences, but it was %d: %v", len(testTable), len(diffs), diffs)
+	}
+	for i, expected := range testTable {
+		if result := diffs[i].String(); result != expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+		}
+	}
+
+	if diffs := a.Items[0].Diff(a.Items[0]); len(diffs) != 0 {
+		t.Errorf("Result should have been no differences, but it was %v", diffs)
+	}
+}
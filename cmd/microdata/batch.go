@@ -0,0 +1,236 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namsral/microdata"
+	"github.com/namsral/microdata/sitemap"
+)
+
+// record is the NDJSON record written for each source in batch mode.
+type record struct {
+	Source   string              `json:"source"`
+	Encoding *microdata.Encoding `json:"encoding,omitempty"`
+	Items    []*microdata.Item   `json:"items,omitempty"`
+	Warnings []microdata.Warning `json:"warnings,omitempty"`
+	Renames  []microdata.Rename  `json:"renames,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// isBatch reports whether the arguments require batch mode: more than one
+// source, a directory or a glob pattern.
+func isBatch(args []string) bool {
+	if len(args) > 1 {
+		return true
+	}
+	for _, arg := range args {
+		if isURL(arg) {
+			continue
+		}
+		if fi, err := os.Stat(arg); err == nil && fi.IsDir() {
+			return true
+		}
+		if strings.ContainsAny(arg, "*?[") {
+			return true
+		}
+	}
+	return false
+}
+
+// expandSources returns the sources named by the arguments and the input
+// list, in order. Directories are walked recursively for files with a base
+// name matching one of the patterns, glob patterns are expanded. The input
+// list holds one source per line, blank lines and lines starting with a "#"
+// are ignored; "-" reads the list from the stdin.
+func expandSources(args []string, inputList string, patterns []string) ([]string, error) {
+	var sources []string
+
+	if inputList != "" {
+		var r io.Reader = os.Stdin
+		if inputList != "-" {
+			f, err := os.Open(inputList)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			r = f
+		}
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			line := strings.TrimSpace(s.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			sources = append(sources, line)
+		}
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, arg := range args {
+		if isURL(arg) {
+			sources = append(sources, arg)
+			continue
+		}
+
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			if matches, err = filepath.Glob(arg); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, m := range matches {
+			fi, err := os.Stat(m)
+			if err != nil || !fi.IsDir() {
+				// Missing files are reported per source.
+				sources = append(sources, m)
+				continue
+			}
+			err = filepath.WalkDir(m, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && matchAny(patterns, d.Name()) {
+					sources = append(sources, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sources, nil
+}
+
+// matchAny reports whether the name matches any of the patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readSitemap returns the page URLs of the sitemap at the given URL or file
+// path, modified since the given date or duration ago when since isn't empty.
+func readSitemap(src, since string) ([]string, error) {
+	r := &sitemap.Reader{}
+	if since != "" {
+		t, err := parseSince(since)
+		if err != nil {
+			return nil, err
+		}
+		r.Since = t
+	}
+
+	urls, err := r.Read(context.Background(), src)
+	if err != nil {
+		return nil, err
+	}
+	locs := make([]string, 0, len(urls))
+	for _, u := range urls {
+		locs = append(locs, u.Loc)
+	}
+	return locs, nil
+}
+
+// parseSince returns the time of a date, a RFC 3339 time or a duration ago.
+func parseSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -since %q: expected a date, a RFC 3339 time or a duration", s)
+	}
+	return t, nil
+}
+
+// runBatch parses the sources using the given number of concurrent workers
+// and writes a record per source to w, in the order of the sources. It
+// returns the number of sources which failed.
+func runBatch(w io.Writer, sources []string, concurrency int, opts *sourceOptions) (int, error) {
+	enc := json.NewEncoder(w)
+	return parseBatch(sources, concurrency, opts, func(rec record) error {
+		return enc.Encode(rec)
+	})
+}
+
+// parseBatch parses the sources using the given number of concurrent workers
+// and calls handle with the record of each source, in the order of the
+// sources. It stops at the first error of handle and returns the number of
+// sources which failed.
+func parseBatch(sources []string, concurrency int, opts *sourceOptions, handle func(record) error) (int, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]chan record, len(sources))
+	for i := range results {
+		results[i] = make(chan record, 1)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < concurrency; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				rec := record{Source: sources[i]}
+				data, err := parseSource(sources[i], opts)
+				if err != nil {
+					rec.Error = err.Error()
+				} else {
+					rec.Encoding = data.Encoding
+					rec.Items = data.Items
+					if opts.warnings {
+						rec.Warnings = data.Warnings
+					}
+					rec.Renames = data.Renames
+				}
+				results[i] <- rec
+			}
+		}()
+	}
+	go func() {
+		for i := range sources {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+	}()
+
+	failed := 0
+	for _, result := range results {
+		rec := <-result
+		if rec.Error != "" {
+			failed++
+		}
+		if err := handle(rec); err != nil {
+			return failed, err
+		}
+	}
+	return failed, nil
+}
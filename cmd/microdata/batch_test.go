@@ -0,0 +1,202 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/namsral/microdata"
+)
+
+// writeFiles writes the files, by path relative to dir, creating their
+// directories.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIsBatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.html": productHTML})
+	file := filepath.Join(dir, "a.html")
+
+	var testTable = []struct {
+		args     []string
+		expected bool
+	}{
+		{nil, false},
+		{[]string{"http://example.com/"}, false},
+		{[]string{file}, false},
+		{[]string{filepath.Join(dir, "missing.html")}, false},
+		{[]string{dir}, true},
+		{[]string{filepath.Join(dir, "*.html")}, true},
+		{[]string{file, "http://example.com/"}, true},
+	}
+
+	for _, test := range testTable {
+		if result := isBatch(test.args); result != test.expected {
+			t.Errorf("%v: Result should have been %t, but it was %t", test.args, test.expected, result)
+		}
+	}
+}
+
+func TestExpandSources(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"site/index.html":     productHTML,
+		"site/docs/page.htm":  productHTML,
+		"site/docs/notes.txt": "notes",
+		"glob/a.html":         productHTML,
+		"glob/b.html":         productHTML,
+		"glob/c.txt":          "c",
+		"list.txt":            "# Pages\nhttp://example.com/a\n\n  http://example.com/b  \n#http://example.com/c\n",
+	})
+
+	args := []string{
+		"http://example.com/first",
+		filepath.Join(dir, "site"),
+		filepath.Join(dir, "glob", "*.html"),
+		filepath.Join(dir, "missing.html"),
+	}
+	sources, err := expandSources(args, filepath.Join(dir, "list.txt"), []string{"*.html", "*.htm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"http://example.com/a",
+		"http://example.com/b",
+		"http://example.com/first",
+		filepath.Join(dir, "site", "docs", "page.htm"),
+		filepath.Join(dir, "site", "index.html"),
+		filepath.Join(dir, "glob", "a.html"),
+		filepath.Join(dir, "glob", "b.html"),
+		filepath.Join(dir, "missing.html"),
+	}
+	if !reflect.DeepEqual(sources, expected) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", expected, sources)
+	}
+
+	sources, err = expandSources([]string{filepath.Join(dir, "site")}, "", []string{"*.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{filepath.Join(dir, "site", "docs", "notes.txt")}
+	if !reflect.DeepEqual(sources, expected) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", expected, sources)
+	}
+
+	if _, err := expandSources(nil, filepath.Join(dir, "missing.txt"), nil); err == nil {
+		t.Error("Result should have been an error, but it was nil")
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	dir := t.TempDir()
+	var sources []string
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("p%02d.html", i)
+		if i%5 == 3 {
+			// Missing files fail.
+			sources = append(sources, filepath.Join(dir, name))
+			continue
+		}
+		writeFiles(t, dir, map[string]string{
+			name: fmt.Sprintf(`<div itemscope itemtype="http://schema.org/Product"><span itemprop="name">P%d</span></div>`, i),
+		})
+		sources = append(sources, filepath.Join(dir, name))
+	}
+
+	var buf bytes.Buffer
+	failed, err := runBatch(&buf, sources, 4, &sourceOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 4 {
+		t.Errorf("Result should have been 4 failed sources, but it was %d", failed)
+	}
+
+	s := bufio.NewScanner(&buf)
+	n := 0
+	for ; s.Scan(); n++ {
+		var rec struct {
+			Source string `json:"source"`
+			Items  []struct {
+				Properties map[string][]string `json:"properties"`
+			} `json:"items"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			t.Fatal(err)
+		}
+		if n >= len(sources) {
+			break
+		}
+		if rec.Source != sources[n] {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", sources[n], rec.Source)
+		}
+		if n%5 == 3 {
+			if rec.Error == "" || len(rec.Items) != 0 {
+				t.Errorf("%s: Result should have been an error record, but it was %s", rec.Source, s.Bytes())
+			}
+			continue
+		}
+		if expected := fmt.Sprintf("P%d", n); len(rec.Items) != 1 || rec.Items[0].Properties["name"][0] != expected {
+			t.Errorf("%s: Result should have been %s, but it was %s", rec.Source, expected, s.Bytes())
+		}
+	}
+	if n != len(sources) {
+		t.Errorf("Result should have been %d records, but it was %d", len(sources), n)
+	}
+}
+
+func TestRunBatchRenames(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.html": `<div itemscope itemtype="https://schema.org/Product"><span itemprop="name">A</span></div>`,
+	})
+
+	var buf bytes.Buffer
+	if _, err := runBatch(&buf, []string{filepath.Join(dir, "a.html")}, 1, &sourceOptions{normalizeVocabulary: true}); err != nil {
+		t.Fatal(err)
+	}
+	var rec record
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	expected := []microdata.Rename{{Path: "items[0]", Kind: "type", Original: "https://schema.org/Product", Name: "http://schema.org/Product"}}
+	if !reflect.DeepEqual(rec.Renames, expected) {
+		t.Errorf("Result should have been %v, but it was %v", expected, rec.Renames)
+	}
+}
+
+func TestParseBatchHandleError(t *testing.T) {
+	sources := []string{"a.html", "b.html", "c.html"}
+	handled := 0
+	_, err := parseBatch(sources, 2, &sourceOptions{}, func(rec record) error {
+		handled++
+		return fmt.Errorf("write failed")
+	})
+	if err == nil || !strings.Contains(err.Error(), "write failed") {
+		t.Errorf("Result should have been the handle error, but it was %v", err)
+	}
+	if handled != 1 {
+		t.Errorf("Result should have been 1 handled record, but it was %d", handled)
+	}
+}
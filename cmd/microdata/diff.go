@@ -0,0 +1,73 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/namsral/microdata"
+)
+
+// diffMain runs the diff subcommand with the given arguments and returns the
+// exit status: 0 when the documents have the same microdata, 1 when they
+// differ and 2 on errors, like diff(1).
+func diffMain(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	baseURL := fs.String("base-url", "", "base url to use for documents read from a file. Defaults to the canonical URL of the document or the file URL.")
+	contentType := fs.String("content-type", "", "content type of documents read from a file.")
+	charset := fs.String("charset", "", "charset of all documents, overriding the content type and the meta elements.")
+	normalize := fs.String("normalize", "", "comma separated normalizations of text values: rendered, zero-width, nfc, collapse, trim or all.")
+	jsonOutput := fs.Bool("json", false, "output the differences as JSON.")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s diff [options] old new:\n", os.Args[0])
+		fs.PrintDefaults()
+		fmt.Fprint(os.Stderr, "\nCompare the HTML Microdata of two HTML5 documents, given as URLs or file paths.")
+		fmt.Fprint(os.Stderr, " Items are matched by itemid or by their structure. Exits with status 1 when the microdata differs.\n")
+	}
+
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	opts := &sourceOptions{baseURL: *baseURL, contentType: *contentType, charset: *charset}
+	var err error
+	if opts.normalization, err = microdata.ParseNormalization(*normalize); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	var docs [2]*microdata.Microdata
+	for i, src := range fs.Args() {
+		data, err := parseSource(src, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		docs[i] = data
+	}
+
+	diffs := microdata.Diff(docs[0], docs[1])
+	if *jsonOutput {
+		b, err := json.MarshalIndent(diffs, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		fmt.Println(string(b))
+	} else {
+		for _, d := range diffs {
+			fmt.Println(d)
+		}
+	}
+
+	if len(diffs) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,244 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/namsral/microdata"
+)
+
+// sqliteSchema creates the tables of the SQLite export, when they don't exist
+// yet, so that every run appends to the database.
+const sqliteSchema = `PRAGMA foreign_keys = ON;
+CREATE TABLE IF NOT EXISTS documents (
+	id INTEGER PRIMARY KEY,
+	url TEXT NOT NULL,
+	encoding TEXT,
+	error TEXT,
+	exported_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS items (
+	id INTEGER PRIMARY KEY,
+	document_id INTEGER NOT NULL REFERENCES documents (id),
+	parent_id INTEGER REFERENCES items (id),
+	path TEXT NOT NULL,
+	itemid TEXT,
+	UNIQUE (document_id, path)
+);
+CREATE TABLE IF NOT EXISTS types (
+	item_id INTEGER NOT NULL REFERENCES items (id),
+	type TEXT NOT NULL,
+	PRIMARY KEY (item_id, type)
+);
+CREATE TABLE IF NOT EXISTS property_values (
+	id INTEGER PRIMARY KEY,
+	item_id INTEGER NOT NULL REFERENCES items (id),
+	property TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	value TEXT,
+	value_item_id INTEGER REFERENCES items (id),
+	lang TEXT,
+	html TEXT
+);
+CREATE INDEX IF NOT EXISTS documents_url ON documents (url);
+CREATE INDEX IF NOT EXISTS types_type ON types (type);
+CREATE INDEX IF NOT EXISTS property_values_item_id ON property_values (item_id);
+CREATE INDEX IF NOT EXISTS property_values_property ON property_values (property);
+CREATE TEMP TABLE IF NOT EXISTS current_document (id INTEGER);
+`
+
+// currentDocument is the SQL expression of the id of the document being
+// inserted.
+const currentDocument = "(SELECT id FROM current_document)"
+
+// exportMain runs the export subcommand with the given arguments and returns
+// the exit status: 0 on success, 1 when sources failed and 2 on errors.
+func exportMain(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	database := fs.String("sqlite", "", "SQLite database to append the microdata to, created when it doesn't exist.")
+	sqlite3 := fs.String("sqlite3", "sqlite3", "path of the sqlite3 command line shell used to write the database.")
+	sqlOutput := fs.Bool("sql", false, "write the SQL statements to stdout instead of running sqlite3.")
+	inputList := fs.String("input-list", "", "file with one URL or file path per line, - for stdin.")
+	include := fs.String("include", "*.html,*.htm", "comma separated glob patterns of the file names to parse when walking a directory.")
+	concurrency := fs.Int("concurrency", 4, "number of sources parsed concurrently.")
+	charset := fs.String("charset", "", "charset of all documents, overriding the content type and the meta elements.")
+	normalize := fs.String("normalize", "", "comma separated normalizations of text values: rendered, zero-width, nfc, collapse, trim or all.")
+	normalizeVocabulary := fs.Bool("normalize-vocabulary", false, "replace the variants of schema.org types and the data-vocabulary.org types by schema.org types.")
+	strict := fs.Bool("strict", false, "fail on markup anomalies, the failed documents are inserted with their error.")
+	types := fs.String("type", "", "comma separated types of the items to keep, e.g. Product or http://schema.org/Product.")
+	excludeTypes := fs.String("exclude-type", "", "comma separated types of the items to remove, also when nested in other items.")
+	nested := fs.Bool("nested", false, "promote the nested items matching -type to top-level items.")
+	htmlMode := fs.String("html", "", "capture the inner HTML of the elements of text values, raw or sanitized, in the html column of property_values.")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s export -sqlite file [options] [url|file|dir ...]:\n", os.Args[0])
+		fs.PrintDefaults()
+		fmt.Fprint(os.Stderr, "\nAppend the HTML Microdata of the sources to a SQLite database, in the tables documents, items, types and property_values.")
+		fmt.Fprint(os.Stderr, " The database is written with the sqlite3 command line shell, one transaction per document.\n")
+	}
+
+	fs.Parse(args)
+	if (*database == "") == !*sqlOutput || (fs.NArg() == 0 && *inputList == "") {
+		fs.Usage()
+		return 2
+	}
+
+	opts := &sourceOptions{
+		charset:             *charset,
+		normalizeVocabulary: *normalizeVocabulary,
+		strict:              *strict,
+		typeFilter:          newTypeFilter(*types, *excludeTypes, *nested),
+	}
+	var err error
+	if opts.normalization, err = microdata.ParseNormalization(*normalize); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if opts.htmlMode, err = microdata.ParseHTMLMode(*htmlMode); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	sources, err := expandSources(fs.Args(), *inputList, strings.Split(*include, ","))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	var w io.Writer = os.Stdout
+	var cmd *exec.Cmd
+	var stdin io.WriteCloser
+	if !*sqlOutput {
+		cmd = exec.Command(*sqlite3, "-bail", *database)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if stdin, err = cmd.StdinPipe(); err == nil {
+			err = cmd.Start()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		w = stdin
+	}
+
+	bw := bufio.NewWriter(w)
+	failed, err := exportSQL(bw, sources, *concurrency, opts)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cmd != nil {
+		stdin.Close()
+		if waitErr := cmd.Wait(); err == nil {
+			err = waitErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// exportSQL parses the sources and writes the SQL statements creating the
+// schema and inserting the microdata of each source. It returns the number of
+// sources which failed; they're inserted as documents with an error.
+func exportSQL(w io.Writer, sources []string, concurrency int, opts *sourceOptions) (int, error) {
+	if _, err := io.WriteString(w, sqliteSchema); err != nil {
+		return 0, err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	return parseBatch(sources, concurrency, opts, func(rec record) error {
+		return writeDocumentSQL(w, rec, now)
+	})
+}
+
+// writeDocumentSQL writes the SQL statements inserting the record's document
+// with its items, in a transaction.
+func writeDocumentSQL(w io.Writer, rec record, exportedAt string) error {
+	var b strings.Builder
+	encoding := ""
+	if rec.Encoding != nil {
+		encoding = rec.Encoding.Name
+	}
+	b.WriteString("BEGIN;\n")
+	fmt.Fprintf(&b, "INSERT INTO documents (url, encoding, error, exported_at) VALUES (%s, %s, %s, %s);\n",
+		sqlString(rec.Source), sqlNullString(encoding), sqlNullString(rec.Error), sqlString(exportedAt))
+	b.WriteString("DELETE FROM current_document;\n")
+	b.WriteString("INSERT INTO current_document VALUES (last_insert_rowid());\n")
+
+	paths := make(map[*microdata.Item]string)
+	for i, item := range rec.Items {
+		if _, ok := paths[item]; !ok {
+			writeItemSQL(&b, paths, fmt.Sprintf("items[%d]", i), "", item)
+		}
+	}
+
+	b.WriteString("COMMIT;\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeItemSQL writes the SQL statements inserting the item at the given path,
+// its types, its nested items and its property values. An item held by more
+// than one property is inserted once, at its first path.
+func writeItemSQL(b *strings.Builder, paths map[*microdata.Item]string, path, parent string, item *microdata.Item) {
+	paths[item] = path
+	parentID := "NULL"
+	if parent != "" {
+		parentID = sqlItemID(parent)
+	}
+	fmt.Fprintf(b, "INSERT INTO items (document_id, parent_id, path, itemid) VALUES (%s, %s, %s, %s);\n",
+		currentDocument, parentID, sqlString(path), sqlNullString(item.ID))
+	for _, t := range item.Types {
+		fmt.Fprintf(b, "INSERT OR IGNORE INTO types (item_id, type) VALUES (%s, %s);\n", sqlItemID(path), sqlString(t))
+	}
+
+	for _, name := range item.PropertyNames() {
+		for i, v := range item.Values(name) {
+			value, valueItemID := "NULL", "NULL"
+			if sub, ok := v.Value.(*microdata.Item); ok {
+				subPath, seen := paths[sub]
+				if !seen {
+					subPath = fmt.Sprintf("%s.%s[%d]", path, name, i)
+					writeItemSQL(b, paths, subPath, path, sub)
+				}
+				valueItemID = sqlItemID(subPath)
+			} else {
+				value = sqlString(fmt.Sprint(v.Value))
+			}
+			fmt.Fprintf(b, "INSERT INTO property_values (item_id, property, position, value, value_item_id, lang, html) VALUES (%s, %s, %d, %s, %s, %s, %s);\n",
+				sqlItemID(path), sqlString(name), i, value, valueItemID, sqlNullString(v.Lang), sqlNullString(v.HTML))
+		}
+	}
+}
+
+// sqlItemID returns the SQL expression of the id of the item at the given
+// path in the current document.
+func sqlItemID(path string) string {
+	return fmt.Sprintf("(SELECT id FROM items WHERE document_id = %s AND path = %s)", currentDocument, sqlString(path))
+}
+
+// sqlString returns s as a SQL string literal.
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlNullString returns s as a SQL string literal, or NULL when s is empty.
+func sqlNullString(s string) string {
+	if s == "" {
+		return "NULL"
+	}
+	return sqlString(s)
+}
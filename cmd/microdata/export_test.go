@@ -0,0 +1,109 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/namsral/microdata"
+)
+
+var airportHTML = `<html><body>
+<div itemscope itemtype="http://schema.org/Airport">
+	<span itemprop="name">O'Hare</span>
+	<span itemprop="iataCode">ORD</span>
+</div>
+<div itemscope itemtype="http://schema.org/Product"><span itemprop="name">Anvil</span></div>
+</body></html>`
+
+// sqlite runs the SQL statements with the sqlite3 shell on the database and
+// returns the output. The test is skipped when sqlite3 isn't installed.
+func sqlite(t *testing.T, db, sql string) string {
+	path, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+	cmd := exec.Command(path, "-bail", db)
+	cmd.Stdin = strings.NewReader(sql)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sqlite3: %v: %s", err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestExportSQL(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "airport.html")
+	if err := os.WriteFile(src, []byte(airportHTML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &sourceOptions{typeFilter: newTypeFilter("Airport", "", false)}
+	var buf bytes.Buffer
+	failed, err := exportSQL(&buf, []string{src, filepath.Join(dir, "missing.html")}, 2, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 1 {
+		t.Errorf("Result should have been 1 failed source, but it was %d", failed)
+	}
+	if !strings.Contains(buf.String(), `'O''Hare'`) {
+		t.Errorf("Result should have contained 'O''Hare', but it was \"%s\"", buf.String())
+	}
+
+	// Every run appends to the database.
+	db := filepath.Join(dir, "export.db")
+	sqlite(t, db, buf.String())
+	sqlite(t, db, buf.String())
+
+	var testTable = []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT count(*) FROM documents;", "4"},
+		{"SELECT count(*) FROM documents WHERE error IS NOT NULL;", "2"},
+		{"SELECT count(*) FROM items;", "2"},
+		{"SELECT DISTINCT type FROM types;", "http://schema.org/Airport"},
+		{"SELECT DISTINCT value FROM property_values WHERE property = 'name';", "O'Hare"},
+	}
+	for _, test := range testTable {
+		if result := sqlite(t, db, test.query); result != test.expected {
+			t.Errorf("%s: Result should have been \"%s\", but it was \"%s\"", test.query, test.expected, result)
+		}
+	}
+}
+
+func TestWriteDocumentSQLSharedItem(t *testing.T) {
+	data, err := microdata.Unflatten([]microdata.Row{
+		{Path: "items[0]", Type: "http://schema.org/Product", Property: "offers", Value: "items[0].offers[0]", Kind: microdata.ItemValue},
+		{Path: "items[0]", Type: "http://schema.org/Product", Property: "cheapest", Value: "items[0].offers[0]", Kind: microdata.ItemValue},
+		{Path: "items[0].offers[0]", Type: "http://schema.org/Offer", Property: "price", Value: "9.99", Kind: microdata.TextValue},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	rec := record{Source: "http://example.com/", Items: data.Items}
+	if err := writeDocumentSQL(&buf, rec, "2015-01-01T00:00:00Z"); err != nil {
+		t.Fatal(err)
+	}
+	if result := strings.Count(buf.String(), "INSERT INTO items "); result != 2 {
+		t.Errorf("Result should have been 2 items, but it was %d", result)
+	}
+
+	db := filepath.Join(t.TempDir(), "export.db")
+	sqlite(t, db, sqliteSchema+buf.String())
+	query := "SELECT v.property, i.path FROM property_values v JOIN items i ON i.id = v.value_item_id ORDER BY v.property;"
+	expected := "cheapest|items[0].offers[0]\noffers|items[0].offers[0]"
+	if result := sqlite(t, db, query); result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
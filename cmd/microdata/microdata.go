@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"strings"
 	"text/template"
 
 	"github.com/namsral/microdata"
@@ -22,15 +23,55 @@ func main() {
 	var data *microdata.Microdata
 	var err error
 
-	baseURL := flag.String("base-url", "http://example.com", "base url to use for the data in the stdin stream.")
-	contentType := flag.String("content-type", "", "content type of the data in the stdin stream.")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			os.Exit(diffMain(os.Args[2:]))
+		case "serve":
+			os.Exit(serveMain(os.Args[2:]))
+		case "warc":
+			os.Exit(warcMain(os.Args[2:]))
+		case "export":
+			os.Exit(exportMain(os.Args[2:]))
+		}
+	}
+
+	baseURL := flag.String("base-url", "", `base url to use for the data in the stdin stream or in files. Defaults
+	to the canonical URL of the document, the file URL or http://example.com for
+	the stdin stream.`)
+	contentType := flag.String("content-type", "", `content type of the data in the stdin stream or in files. Defaults to
+	detecting the charset from the byte order mark or the meta elements.`)
+	charset := flag.String("charset", "", `charset of all documents, e.g. shift_jis, overriding the content type and
+	the meta elements. Only a byte order mark takes precedence.`)
+	normalize := flag.String("normalize", "", `comma separated normalizations of text values: rendered, zero-width, nfc,
+	collapse, trim or all. Defaults to the text as specified, including the
+	whitespace of the markup.`)
+	normalizeVocabulary := flag.Bool("normalize-vocabulary", false, `replace the https, schema.org/ and www variants of schema.org types by
+	http://schema.org/ types and the legacy data-vocabulary.org types and
+	properties by their schema.org equivalents. The renames are reported on
+	stderr or in the batch records.`)
+	warnings := flag.Bool("warnings", false, "report markup anomalies, like itemrefs to missing IDs, on stderr or in the batch records.")
+	strict := flag.Bool("strict", false, "fail on markup anomalies, see -warnings.")
+	types := flag.String("type", "", `comma separated types of the items to keep, e.g. Product or
+	http://schema.org/Product. Names without a vocabulary match the types ending
+	with them.`)
+	excludeTypes := flag.String("exclude-type", "", "comma separated types of the items to remove, also when nested in other items.")
+	nested := flag.Bool("nested", false, "promote the nested items matching -type to top-level items.")
+	htmlMode := flag.String("html", "", `capture the inner HTML of the elements of text values, raw or sanitized,
+	available to -format templates through the Values method of items. Requires -format.`)
 	format := flag.String("format", "{{. |jsonMarshal }}", `alternate format for the output of the
 	microdata, using the syntax of package html/template. The default output is
 	equivalent to -f '{{. |jsonMarshal }}'. The struct being passed to the
 	template is:
 		
 		type Microdata struct
-			Items []*Item 'json:"items"'
+			Items    []*Item 'json:"items"'
+			Encoding *Encoding
+		}
+
+		type Encoding struct {
+			Name   string
+			Source string
 		}
 
 		type Item struct {
@@ -43,37 +84,147 @@ func main() {
 		
 		type ValueList []interface{}
 
+	The method Values of an item returns the values of a property with their
+	language and, with -html, their HTML:
+
+		func (i *Item) Values(property string) []Value
+
+		type Value struct {
+			Value interface{}
+			Lang  string
+			HTML  string
+		}
+
 	The template function "jsonMarshal" calls json.Marshal
 `)
+	inputList := flag.String("input-list", "", "file with one URL or file path per line, - for stdin. Enables batch mode.")
+	sitemapSrc := flag.String("sitemap", "", "URL or file of a sitemap or sitemap index whose pages are parsed. Enables batch mode.")
+	since := flag.String("since", "", `only parse the sitemap pages modified since the given date, e.g.
+	2006-01-02 or 2006-01-02T15:04:05Z, or the given duration ago, e.g. 24h.`)
+	include := flag.String("include", "*.html,*.htm", "comma separated glob patterns of the file names to parse when walking a directory.")
+	concurrency := flag.Int("concurrency", 4, "number of sources parsed concurrently in batch mode.")
+	cacheDir := flag.String("cache", "", "directory to cache fetched URLs in, revalidated with conditional requests.")
+	output := flag.String("output", "", "output format, one of "+strings.Join(outputNames(), ", ")+". Overrides -format. Not supported in batch mode.")
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage of %s [options] [url]:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage of %s [options] [url|file|dir ...]:\n", os.Args[0])
 		flag.PrintDefaults()
 		fmt.Fprint(os.Stderr, "\nExtract the HTML Microdata from a HTML5 document. Format to JSON or using the syntax of package html/template.")
-		fmt.Fprint(os.Stderr, " Provide an URL to a valid HTML5 document or stream a valid HTML5 document through stdin.\n")
+		fmt.Fprint(os.Stderr, " Provide an URL or a file path to a valid HTML5 document or stream a valid HTML5 document through stdin.\n")
+		fmt.Fprint(os.Stderr, "\nProvide multiple URLs, files, directories or glob patterns, an -input-list or a -sitemap, to parse them in batch mode.")
+		fmt.Fprint(os.Stderr, " Batch mode writes one JSON record per line with the source, its items or its error.\n")
+		fmt.Fprintf(os.Stderr, "\nCompare the HTML Microdata of two documents with %s diff old new.\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Serve an HTTP extraction API with %s serve.\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Extract the HTML Microdata from WARC archives with %s warc file.warc.gz.\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Append the HTML Microdata of many documents to a SQLite database with %s export -sqlite crawl.db dir.\n", os.Args[0])
 	}
 
 	flag.Parse()
 
+	opts := &sourceOptions{
+		baseURL:             *baseURL,
+		contentType:         *contentType,
+		charset:             *charset,
+		normalizeVocabulary: *normalizeVocabulary,
+		strict:              *strict,
+		warnings:            *warnings,
+		typeFilter:          newTypeFilter(*types, *excludeTypes, *nested),
+	}
+	if opts.normalization, err = microdata.ParseNormalization(*normalize); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if opts.htmlMode, err = microdata.ParseHTMLMode(*htmlMode); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if opts.htmlMode != microdata.NoHTML && (!isFlagSet("format") || isFlagSet("output")) {
+		// Only -format templates can read the HTML of the values.
+		fmt.Println("-html requires -format and no -output, the other outputs don't include the HTML of values")
+		os.Exit(1)
+	}
+	if *cacheDir != "" {
+		opts.cache, err = microdata.NewCache(*cacheDir)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if *inputList != "" || *sitemapSrc != "" || isBatch(flag.Args()) {
+		// Batch mode writes NDJSON records, the other formats can't hold
+		// more than one document.
+		for _, name := range []string{"output", "format"} {
+			if isFlagSet(name) {
+				fmt.Printf("-%s is not supported in batch mode, which writes one JSON record per source\n", name)
+				os.Exit(1)
+			}
+		}
+		sources, err := expandSources(flag.Args(), *inputList, strings.Split(*include, ","))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if *sitemapSrc != "" {
+			urls, err := readSitemap(*sitemapSrc, *since)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			sources = append(sources, urls...)
+		}
+		failed, err := runBatch(os.Stdout, sources, *concurrency, opts)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if opts.cache != nil {
+			stats := opts.cache.Stats()
+			fmt.Fprintf(os.Stderr, "cache: %d requests, %d hits, %d misses, %d errors\n", stats.Requests, stats.Hits, stats.Misses, stats.Errors)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Fetch and parse microdata
 	switch len(flag.Args()) {
 	case 0:
-		u, err := url.Parse(*baseURL)
+		u, _ := url.Parse("http://example.com")
+		data, err = parseDocument(os.Stdin, u, opts)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		data, err = microdata.ParseHTML(os.Stdin, *contentType, u)
+	default:
+		data, err = parseSource(flag.Args()[0], opts)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
-	default:
-		data, err = microdata.ParseURL(flag.Args()[0])
-		if err != nil {
+	}
+
+	if opts.warnings {
+		for _, w := range data.Warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s (%s)\n", w.Error(), w.Code)
+		}
+	}
+	for _, r := range data.Renames {
+		fmt.Fprintf(os.Stderr, "rename: %s\n", r)
+	}
+
+	if *output != "" {
+		write, ok := outputs[*output]
+		if !ok {
+			fmt.Printf("unknown output format %q\n", *output)
+			os.Exit(1)
+		}
+		if err := write(os.Stdout, data); err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
+		return
 	}
 
 	t := template.Must(template.New("format").Funcs(fnmap).Parse(*format))
@@ -91,3 +242,14 @@ func jsonMarshal(data interface{}) (string, error) {
 	}
 	return string(b), nil
 }
+
+// isFlagSet reports whether the named flag was set on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
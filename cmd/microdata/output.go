@@ -0,0 +1,369 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/namsral/microdata"
+)
+
+// outputs maps the names accepted by the -output flag to their writers.
+var outputs = map[string]func(io.Writer, *microdata.Microdata) error{
+	"json":         writeJSON,
+	"json-compact": writeCompactJSON,
+	"jsonld":       writeJSONLD,
+	"ntriples":     writeNTriples,
+	"ndjson":       writeNDJSON,
+	"yaml":         writeYAML,
+	"csv":          writeCSV,
+	"triples-csv":  writeTriplesCSV,
+	"xml":          writeXML,
+	"tree":         writeTree,
+	"dot":          writeDOT,
+}
+
+// outputNames returns the sorted names of the output formats.
+func outputNames() []string {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeJSON writes the microdata as indented JSON.
+func writeJSON(w io.Writer, data *microdata.Microdata) error {
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// writeCompactJSON writes the microdata as JSON on a single line.
+func writeCompactJSON(w io.Writer, data *microdata.Microdata) error {
+	return json.NewEncoder(w).Encode(data)
+}
+
+// writeJSONLD writes the microdata as a JSON-LD document.
+func writeJSONLD(w io.Writer, data *microdata.Microdata) error {
+	return data.WriteJSONLD(w)
+}
+
+// writeNTriples writes the microdata as RDF in the N-Triples format.
+func writeNTriples(w io.Writer, data *microdata.Microdata) error {
+	return data.WriteNTriples(w)
+}
+
+// writeDOT writes the microdata as a GraphViz graph.
+func writeDOT(w io.Writer, data *microdata.Microdata) error {
+	return data.WriteDOT(w)
+}
+
+// writeNDJSON writes each top-level item as JSON on a line of its own.
+func writeNDJSON(w io.Writer, data *microdata.Microdata) error {
+	enc := json.NewEncoder(w)
+	for _, item := range data.Items {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// plainKey matches the keys which don't need quoting in YAML.
+var plainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// writeYAML writes the microdata as a YAML document. Properties are written in
+// document order.
+func writeYAML(w io.Writer, data *microdata.Microdata) error {
+	var buf bytes.Buffer
+	if len(data.Items) == 0 {
+		buf.WriteString("items: []\n")
+	} else {
+		buf.WriteString("items:\n")
+		for _, item := range data.Items {
+			writeYAMLItem(&buf, item, "  ")
+		}
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// writeYAMLItem writes the item as a YAML sequence entry at the given indent.
+func writeYAMLItem(buf *bytes.Buffer, item *microdata.Item, indent string) {
+	pad := indent + "  "
+
+	fmt.Fprintf(buf, "%s- type:", indent)
+	if len(item.Types) == 0 {
+		buf.WriteString(" []\n")
+	} else {
+		buf.WriteString("\n")
+		for _, t := range item.Types {
+			fmt.Fprintf(buf, "%s  - %s\n", pad, yamlString(t))
+		}
+	}
+
+	fmt.Fprintf(buf, "%sproperties:", pad)
+	if len(item.Properties) == 0 {
+		buf.WriteString(" {}\n")
+	} else {
+		buf.WriteString("\n")
+		for _, name := range item.PropertyNames() {
+			key := name
+			if !plainKey.MatchString(key) {
+				key = yamlString(key)
+			}
+			fmt.Fprintf(buf, "%s  %s:\n", pad, key)
+			for _, v := range item.Properties[name] {
+				switch v := v.(type) {
+				case *microdata.Item:
+					writeYAMLItem(buf, v, pad+"    ")
+				default:
+					fmt.Fprintf(buf, "%s    - %s\n", pad, yamlString(fmt.Sprint(v)))
+				}
+			}
+		}
+	}
+
+	if item.ID != "" {
+		fmt.Fprintf(buf, "%sid: %s\n", pad, yamlString(item.ID))
+	}
+}
+
+// yamlString returns s as a double-quoted YAML scalar. JSON strings are valid
+// double-quoted YAML scalars.
+func yamlString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// writeCSV writes one row per top-level item. The columns are the flattened
+// property paths of all items, e.g. "name[0]" or "offers[0].price[0]",
+// preceded by the "type" and "id" columns. Nested items add a type column and
+// an id column when they have an itemid. Multiple types are separated by a
+// space.
+func writeCSV(w io.Writer, data *microdata.Microdata) error {
+	columns := []string{"type", "id"}
+	index := map[string]int{"type": 0, "id": 1}
+
+	var rows []map[string]string
+	for _, item := range data.Items {
+		row := make(map[string]string)
+		flattenItem(row, "", item)
+		rows = append(rows, row)
+	}
+
+	// Add the columns in order of first appearance.
+	for _, item := range data.Items {
+		var paths []string
+		collectPaths(&paths, "", item)
+		for _, path := range paths {
+			if _, ok := index[path]; !ok {
+				index[path] = len(columns)
+				columns = append(columns, path)
+			}
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for path, value := range row {
+			record[index[path]] = value
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// flattenItem adds the flattened property paths and values of the item to the
+// given row.
+func flattenItem(row map[string]string, prefix string, item *microdata.Item) {
+	row[prefix+"type"] = strings.Join(item.Types, " ")
+	if item.ID != "" {
+		row[prefix+"id"] = item.ID
+	}
+	for _, name := range item.PropertyNames() {
+		for i, v := range item.Properties[name] {
+			path := fmt.Sprintf("%s%s[%d]", prefix, name, i)
+			if sub, ok := v.(*microdata.Item); ok {
+				flattenItem(row, path+".", sub)
+				continue
+			}
+			row[path] = fmt.Sprint(v)
+		}
+	}
+}
+
+// collectPaths appends the flattened property paths of the item in document
+// order.
+func collectPaths(paths *[]string, prefix string, item *microdata.Item) {
+	if prefix != "" {
+		*paths = append(*paths, prefix+"type")
+		if item.ID != "" {
+			*paths = append(*paths, prefix+"id")
+		}
+	}
+	for _, name := range item.PropertyNames() {
+		for i, v := range item.Properties[name] {
+			path := fmt.Sprintf("%s%s[%d]", prefix, name, i)
+			if sub, ok := v.(*microdata.Item); ok {
+				collectPaths(paths, path+".", sub)
+				continue
+			}
+			*paths = append(*paths, path)
+		}
+	}
+}
+
+// writeTriplesCSV writes one row per property value of the items and their
+// nested items, see microdata.Microdata.Flatten. The rows are read back with
+// microdata.Unflatten.
+func writeTriplesCSV(w io.Writer, data *microdata.Microdata) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"path", "type", "id", "property", "value", "kind"}); err != nil {
+		return err
+	}
+	for _, row := range data.Flatten() {
+		record := []string{row.Path, row.Type, row.ID, row.Property, row.Value, string(row.Kind)}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type xmlMicrodata struct {
+	XMLName xml.Name   `xml:"microdata"`
+	Items   []*xmlItem `xml:"item"`
+}
+
+type xmlItem struct {
+	XMLName    xml.Name       `xml:"item"`
+	ID         string         `xml:"id,attr,omitempty"`
+	Types      []string       `xml:"type"`
+	Properties []*xmlProperty `xml:"property"`
+}
+
+type xmlProperty struct {
+	Name   string        `xml:"name,attr"`
+	Values []interface{} `xml:"value"`
+}
+
+type xmlValue struct {
+	XMLName xml.Name `xml:"value"`
+	Text    string   `xml:",chardata"`
+}
+
+// newXMLItem returns the XML representation of the item.
+func newXMLItem(item *microdata.Item) *xmlItem {
+	x := &xmlItem{ID: item.ID, Types: item.Types}
+	for _, name := range item.PropertyNames() {
+		p := &xmlProperty{Name: name}
+		for _, v := range item.Properties[name] {
+			if sub, ok := v.(*microdata.Item); ok {
+				p.Values = append(p.Values, newXMLItem(sub))
+				continue
+			}
+			p.Values = append(p.Values, &xmlValue{Text: fmt.Sprint(v)})
+		}
+		x.Properties = append(x.Properties, p)
+	}
+	return x
+}
+
+// writeXML writes the microdata as an XML document. Each property element
+// holds its text values as value elements and its nested items as item
+// elements, in document order.
+func writeXML(w io.Writer, data *microdata.Microdata) error {
+	doc := &xmlMicrodata{}
+	for _, item := range data.Items {
+		doc.Items = append(doc.Items, newXMLItem(item))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeTree writes the microdata as an indented tree for humans.
+func writeTree(w io.Writer, data *microdata.Microdata) error {
+	var buf bytes.Buffer
+	for _, item := range data.Items {
+		buf.WriteString(treeLabel(item) + "\n")
+		writeTreeItem(&buf, item, "")
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// writeTreeItem writes the properties of the item as branches below the
+// given prefix.
+func writeTreeItem(buf *bytes.Buffer, item *microdata.Item, prefix string) {
+	type branch struct {
+		name  string
+		value interface{}
+	}
+	var branches []branch
+	for _, name := range item.PropertyNames() {
+		for _, v := range item.Properties[name] {
+			branches = append(branches, branch{name, v})
+		}
+	}
+
+	for i, b := range branches {
+		edge, next := "├── ", "│   "
+		if i == len(branches)-1 {
+			edge, next = "└── ", "    "
+		}
+		if sub, ok := b.value.(*microdata.Item); ok {
+			fmt.Fprintf(buf, "%s%s%s: %s\n", prefix, edge, b.name, treeLabel(sub))
+			writeTreeItem(buf, sub, prefix+next)
+			continue
+		}
+		fmt.Fprintf(buf, "%s%s%s: %q\n", prefix, edge, b.name, b.value)
+	}
+}
+
+// treeLabel returns the label of the item in the tree view, its types followed
+// by its itemid.
+func treeLabel(item *microdata.Item) string {
+	label := strings.Join(item.Types, " ")
+	if label == "" {
+		label = "(item)"
+	}
+	if item.ID != "" {
+		label += " <" + item.ID + ">"
+	}
+	return label
+}
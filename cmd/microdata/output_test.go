@@ -0,0 +1,121 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/namsral/microdata"
+)
+
+// outputHTML holds an item with a value with quotes and a line break, a
+// property with several values, a property name which is an absolute URL and
+// a nested item.
+var outputHTML = `<div itemscope itemtype="http://schema.org/Product" itemid="urn:sku:1"><span itemprop="name">The "Anvil"
+Heavy</span><span itemprop="color">red</span><span itemprop="color">black</span><span itemprop="http://purl.org/dc/terms/title">Anvil</span><div itemprop="offers" itemscope itemtype="http://schema.org/Offer"><span itemprop="price">9.99</span></div></div>`
+
+func TestOutputs(t *testing.T) {
+	u, _ := url.Parse("http://example.com/")
+	data, err := microdata.ParseHTML(strings.NewReader(outputHTML), "text/html", u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testTable = []struct {
+		output   string
+		expected string
+	}{
+		{"yaml", `items:
+  - type:
+      - "http://schema.org/Product"
+    properties:
+      name:
+        - "The \"Anvil\"\nHeavy"
+      color:
+        - "red"
+        - "black"
+      "http://purl.org/dc/terms/title":
+        - "Anvil"
+      offers:
+        - type:
+            - "http://schema.org/Offer"
+          properties:
+            price:
+              - "9.99"
+    id: "urn:sku:1"
+`},
+		{"csv", `type,id,name[0],color[0],color[1],http://purl.org/dc/terms/title[0],offers[0].type,offers[0].price[0]
+http://schema.org/Product,urn:sku:1,"The ""Anvil""
+Heavy",red,black,Anvil,http://schema.org/Offer,9.99
+`},
+		{"xml", `<?xml version="1.0" encoding="UTF-8"?>
+<microdata>
+  <item id="urn:sku:1">
+    <type>http://schema.org/Product</type>
+    <property name="name">
+      <value>The &#34;Anvil&#34;&#xA;Heavy</value>
+    </property>
+    <property name="color">
+      <value>red</value>
+      <value>black</value>
+    </property>
+    <property name="http://purl.org/dc/terms/title">
+      <value>Anvil</value>
+    </property>
+    <property name="offers">
+      <item>
+        <type>http://schema.org/Offer</type>
+        <property name="price">
+          <value>9.99</value>
+        </property>
+      </item>
+    </property>
+  </item>
+</microdata>
+`},
+		{"tree", `http://schema.org/Product <urn:sku:1>
+├── name: "The \"Anvil\"\nHeavy"
+├── color: "red"
+├── color: "black"
+├── http://purl.org/dc/terms/title: "Anvil"
+└── offers: http://schema.org/Offer
+    └── price: "9.99"
+`},
+	}
+
+	for _, test := range testTable {
+		var buf bytes.Buffer
+		if err := outputs[test.output](&buf, data); err != nil {
+			t.Fatal(err)
+		}
+		if result := buf.String(); result != test.expected {
+			t.Errorf("%s: Result should have been \"%s\", but it was \"%s\"", test.output, test.expected, result)
+		}
+	}
+}
+
+func TestOutputsEmpty(t *testing.T) {
+	var testTable = []struct {
+		output   string
+		expected string
+	}{
+		{"yaml", "items: []\n"},
+		{"csv", "type,id\n"},
+		{"xml", "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<microdata></microdata>\n"},
+		{"tree", ""},
+	}
+
+	for _, test := range testTable {
+		var buf bytes.Buffer
+		if err := outputs[test.output](&buf, &microdata.Microdata{}); err != nil {
+			t.Fatal(err)
+		}
+		if result := buf.String(); result != test.expected {
+			t.Errorf("%s: Result should have been %q, but it was %q", test.output, test.expected, result)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/namsral/microdata"
+)
+
+// mediaTypes maps the formats of the extraction API to their media types.
+var mediaTypes = map[string]string{
+	"json":     "application/json",
+	"jsonld":   "application/ld+json",
+	"ntriples": "application/n-triples",
+}
+
+// serveMain runs the serve subcommand with the given arguments and returns the
+// exit status.
+func serveMain(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on.")
+	maxBody := fs.Int64("max-body", 10<<20, "maximum size in bytes of a request body.")
+	fetch := fs.Bool("fetch", false, "allow requests to fetch documents by URL, from public addresses only.")
+	timeout := fs.Duration("timeout", 30*time.Second, "maximum duration of a request.")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s serve [options]:\n", os.Args[0])
+		fs.PrintDefaults()
+		fmt.Fprint(os.Stderr, `
+Serve an HTTP API to extract the HTML Microdata from HTML5 documents.
+
+  POST /extract   extract the microdata of the HTML document in the request
+                  body, or of the document at the "url" parameter when
+                  started with -fetch
+  GET  /healthz   report the health of the server
+  GET  /metrics   report the metrics of the server
+
+The /extract endpoint accepts the parameters "url", "base_url", "profile"
+(one of `+strings.Join(profileNames(), ", ")+`) and "format" (one of json, jsonld,
+ntriples). Without a format the response is negotiated using the Accept
+header.
+`)
+	}
+	fs.Parse(args)
+
+	s := newServer(*maxBody, *fetch, *timeout)
+	srv := &http.Server{
+		Addr:         *addr,
+		Handler:      http.TimeoutHandler(s, *timeout, "request timeout\n"),
+		ReadTimeout:  *timeout,
+		WriteTimeout: *timeout + time.Second,
+	}
+	log.Printf("listening on %s", *addr)
+	if err := srv.ListenAndServe(); err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
+
+// profileNames returns the sorted names of the validation profiles.
+func profileNames() []string {
+	names := make([]string, 0, len(microdata.Profiles))
+	for name := range microdata.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// server serves the extraction API.
+type server struct {
+	mux     *http.ServeMux
+	maxBody int64
+	fetch   bool
+
+	// client fetches the documents by URL.
+	client *http.Client
+
+	mu       sync.Mutex
+	requests map[int]uint64
+	items    uint64
+}
+
+// newServer returns a server which limits request bodies and fetched
+// documents to maxBody bytes and fetches documents by URL when fetch is true,
+// within the given timeout.
+func newServer(maxBody int64, fetch bool, timeout time.Duration) *server {
+	s := &server{
+		mux:     http.NewServeMux(),
+		maxBody: maxBody,
+		fetch:   fetch,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext:         publicDialer(timeout).DialContext,
+				TLSHandshakeTimeout: timeout,
+			},
+		},
+		requests: make(map[int]uint64),
+	}
+	s.mux.HandleFunc("/extract", s.handleExtract)
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	s.mux.HandleFunc("/metrics", s.handleMetrics)
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// record counts a request to the extraction endpoint.
+func (s *server) record(code int, items int) {
+	s.mu.Lock()
+	s.requests[code]++
+	s.items += uint64(items)
+	s.mu.Unlock()
+}
+
+// fail writes an error response and counts the request.
+func (s *server) fail(w http.ResponseWriter, code int, err error) {
+	s.record(code, 0)
+	http.Error(w, err.Error(), code)
+}
+
+func (s *server) handleExtract(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		s.fail(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxBody)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = negotiate(r.Header.Get("Accept"))
+	}
+	mediaType, ok := mediaTypes[format]
+	if !ok {
+		s.fail(w, http.StatusNotAcceptable, fmt.Errorf("unsupported format %q", format))
+		return
+	}
+
+	var profile microdata.Profile
+	if name := r.URL.Query().Get("profile"); name != "" {
+		if profile, ok = microdata.Profiles[name]; !ok {
+			s.fail(w, http.StatusBadRequest, fmt.Errorf("unknown profile %q", name))
+			return
+		}
+	}
+
+	data, code, err := s.extract(r)
+	if err != nil {
+		s.fail(w, code, err)
+		return
+	}
+
+	var violations []microdata.Violation
+	if profile != nil {
+		violations = profile.Validate(data)
+		w.Header().Set("X-Microdata-Violations", strconv.Itoa(len(violations)))
+	}
+	w.Header().Set("X-Microdata-Items", strconv.Itoa(len(data.Items)))
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Vary", "Accept")
+	s.record(http.StatusOK, len(data.Items))
+
+	switch format {
+	case "jsonld":
+		data.WriteJSONLD(w)
+	case "ntriples":
+		data.WriteNTriples(w)
+	default:
+		json.NewEncoder(w).Encode(struct {
+			*microdata.Microdata
+			Violations []microdata.Violation `json:"violations,omitempty"`
+		}{data, violations})
+	}
+}
+
+// extract returns the microdata of the document in the request body or at
+// the url parameter, and the status code to report on errors.
+func (s *server) extract(r *http.Request) (*microdata.Microdata, int, error) {
+	if ct := r.Header.Get("Content-Type"); strings.HasPrefix(ct, "application/x-www-form-urlencoded") {
+		if err := r.ParseForm(); err != nil {
+			return nil, requestErrorCode(err), err
+		}
+	}
+
+	if src := r.FormValue("url"); src != "" {
+		if !s.fetch {
+			return nil, http.StatusForbidden, errors.New("fetching documents by URL is disabled")
+		}
+		if !isURL(src) {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid url %q", src)
+		}
+		data, err := s.fetchURL(src)
+		if err != nil {
+			return nil, http.StatusBadGateway, err
+		}
+		return data, 0, nil
+	}
+
+	baseURL := r.FormValue("base_url")
+	if baseURL == "" {
+		baseURL = "http://example.com"
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/html"
+	}
+	data, err := microdata.ParseHTML(r.Body, contentType, u, microdata.WithLanguage(r.Header.Get("Content-Language")))
+	if err != nil {
+		return nil, requestErrorCode(err), err
+	}
+	return data, 0, nil
+}
+
+// fetchURL returns the microdata of the document at the URL, fetched with the
+// client of the server. Documents larger than maxBody bytes are an error.
+func (s *server) fetchURL(src string) (*microdata.Microdata, error) {
+	resp, err := s.client.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body := http.MaxBytesReader(nil, resp.Body, s.maxBody)
+	data, err := microdata.ParseHTML(body, resp.Header.Get("Content-Type"), resp.Request.URL,
+		microdata.WithLanguage(resp.Header.Get("Content-Language")))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return nil, fmt.Errorf("document at %s exceeds %d bytes", src, s.maxBody)
+	}
+	return data, err
+}
+
+// publicDialer returns a dialer which refuses to connect to loopback,
+// private, link-local, multicast and unspecified addresses, so that requests
+// can't reach the services of the network of the server. The addresses are
+// checked after name resolution.
+func publicDialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+				return fmt.Errorf("fetching from address %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+}
+
+// requestErrorCode returns the status code for an error reading the request.
+func requestErrorCode(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// negotiate returns the format of the media type with the highest quality in
+// the Accept header, json when the header is empty and "" when none of the
+// media types is supported.
+func negotiate(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return "json"
+	}
+
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		q := 1.0
+		for _, p := range params[1:] {
+			if k, v, ok := strings.Cut(strings.TrimSpace(p), "="); ok && k == "q" {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+
+		var format string
+		switch mediaType {
+		case "application/json", "application/*", "*/*":
+			format = "json"
+		case "application/ld+json":
+			format = "jsonld"
+		case "application/n-triples", "text/plain":
+			format = "ntriples"
+		default:
+			continue
+		}
+		if q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best
+}
+
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, "ok\n")
+}
+
+// handleMetrics writes the metrics in the Prometheus text format.
+func (s *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	codes := make([]int, 0, len(s.requests))
+	for code := range s.requests {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP microdata_requests_total Extraction requests by status code.")
+	fmt.Fprintln(w, "# TYPE microdata_requests_total counter")
+	for _, code := range codes {
+		fmt.Fprintf(w, "microdata_requests_total{code=\"%d\"} %d\n", code, s.requests[code])
+	}
+	fmt.Fprintln(w, "# HELP microdata_items_total Top-level items extracted.")
+	fmt.Fprintln(w, "# TYPE microdata_items_total counter")
+	fmt.Fprintf(w, "microdata_items_total %d\n", s.items)
+	s.mu.Unlock()
+}
@@ -0,0 +1,136 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var productHTML = `<div itemscope itemtype="http://schema.org/Product"><span itemprop="name">Anvil</span></div>`
+
+func TestNegotiate(t *testing.T) {
+	var testTable = []struct {
+		accept   string
+		expected string
+	}{
+		{"", "json"},
+		{"application/json", "json"},
+		{"*/*", "json"},
+		{"application/ld+json", "jsonld"},
+		{"text/plain", "ntriples"},
+		{"application/json;q=0.5, application/n-triples", "ntriples"},
+		{"application/ld+json;q=0.9, application/json;q=0.1", "jsonld"},
+		{"text/html", ""},
+	}
+
+	for _, test := range testTable {
+		if result := negotiate(test.accept); result != test.expected {
+			t.Errorf("%q: Result should have been %q, but it was %q", test.accept, test.expected, result)
+		}
+	}
+}
+
+func TestServeExtract(t *testing.T) {
+	s := newServer(1<<10, false, time.Second)
+
+	var testTable = []struct {
+		method string
+		target string
+		accept string
+		body   string
+		code   int
+	}{
+		{http.MethodPost, "/extract", "", productHTML, http.StatusOK},
+		{http.MethodPost, "/extract?format=ntriples", "", productHTML, http.StatusOK},
+		{http.MethodGet, "/extract", "", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/extract", "text/html", productHTML, http.StatusNotAcceptable},
+		{http.MethodPost, "/extract?format=xml", "", productHTML, http.StatusNotAcceptable},
+		{http.MethodPost, "/extract?profile=unknown", "", productHTML, http.StatusBadRequest},
+		{http.MethodPost, "/extract", "", strings.Repeat(productHTML, 20), http.StatusRequestEntityTooLarge},
+		{http.MethodPost, "/extract?url=http://example.com/", "", "", http.StatusForbidden},
+	}
+
+	for _, test := range testTable {
+		r := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("%s %s: Result should have been %d, but it was %d: %s", test.method, test.target, test.code, w.Code, w.Body)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/extract", strings.NewReader(productHTML))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if result := w.Header().Get("X-Microdata-Items"); result != "1" {
+		t.Errorf("Result should have been 1 item, but it was %q", result)
+	}
+	if !strings.Contains(w.Body.String(), `"name":["Anvil"]`) {
+		t.Errorf("Result should have been the product, but it was %q", w.Body)
+	}
+}
+
+func TestServeMetrics(t *testing.T) {
+	s := newServer(1<<10, false, time.Second)
+	for _, method := range []string{http.MethodPost, http.MethodPost, http.MethodGet} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/extract", strings.NewReader(productHTML)))
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, expected := range []string{
+		`microdata_requests_total{code="200"} 2`,
+		`microdata_requests_total{code="405"} 1`,
+		"microdata_items_total 2",
+	} {
+		if !strings.Contains(w.Body.String(), expected) {
+			t.Errorf("Result should have contained %q, but it was %q", expected, w.Body)
+		}
+	}
+}
+
+func TestServeFetch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if r.URL.Path == "/large" {
+			io.WriteString(w, strings.Repeat(productHTML, 20))
+			return
+		}
+		io.WriteString(w, productHTML)
+	}))
+	defer ts.Close()
+
+	extract := func(s *server, target string) *httptest.ResponseRecorder {
+		form := url.Values{"url": {target}}
+		r := httptest.NewRequest(http.MethodPost, "/extract", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+
+	// The test server listens on a loopback address, which the default
+	// client refuses.
+	s := newServer(1<<10, true, time.Second)
+	if w := extract(s, ts.URL); w.Code != http.StatusBadGateway || !strings.Contains(w.Body.String(), "not allowed") {
+		t.Errorf("Result should have been a refused address, but it was %d: %s", w.Code, w.Body)
+	}
+
+	s.client = ts.Client()
+	if w := extract(s, ts.URL); w.Code != http.StatusOK || w.Header().Get("X-Microdata-Items") != "1" {
+		t.Errorf("Result should have been 1 item, but it was %d: %s", w.Code, w.Body)
+	}
+	if w := extract(s, ts.URL+"/large"); w.Code != http.StatusBadGateway || !strings.Contains(w.Body.String(), "exceeds") {
+		t.Errorf("Result should have been a too large document, but it was %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/namsral/microdata"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// isURL reports whether the source is fetched over HTTP.
+func isURL(src string) bool {
+	return strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")
+}
+
+// sourceOptions holds the options for parsing sources.
+type sourceOptions struct {
+	// baseURL and contentType override the base URL and the content type of
+	// files and the stdin stream, see parseDocument.
+	baseURL     string
+	contentType string
+
+	// charset, when set, overrides the charset of all documents, see
+	// microdata.WithEncoding.
+	charset string
+
+	// normalization is the set of normalizations of text values.
+	normalization microdata.Normalization
+
+	// htmlMode selects how the HTML of text values is captured.
+	htmlMode microdata.HTMLMode
+
+	// normalizeVocabulary enables the normalization of types and property
+	// names, see microdata.WithVocabularyNormalization.
+	normalizeVocabulary bool
+
+	// strict fails parsing on markup anomalies, warnings reports them.
+	strict   bool
+	warnings bool
+
+	// typeFilter, when set, selects the items by type.
+	typeFilter *microdata.TypeFilter
+
+	// cache, when set, caches fetched documents.
+	cache *microdata.Cache
+}
+
+// parseOptions returns the options of microdata.ParseHTML.
+func (opts *sourceOptions) parseOptions() []microdata.Option {
+	var options []microdata.Option
+	if opts.charset != "" {
+		options = append(options, microdata.WithEncoding(opts.charset))
+	}
+	if opts.normalization != 0 {
+		options = append(options, microdata.WithNormalization(opts.normalization))
+	}
+	if opts.htmlMode != microdata.NoHTML {
+		options = append(options, microdata.WithHTML(opts.htmlMode))
+	}
+	if opts.normalizeVocabulary {
+		options = append(options, microdata.WithVocabularyNormalization())
+	}
+	if opts.strict {
+		options = append(options, microdata.WithStrict())
+	}
+	if opts.typeFilter != nil {
+		options = append(options, microdata.WithTypeFilter(opts.typeFilter))
+	}
+	return options
+}
+
+// newTypeFilter returns the type filter of the comma separated types to keep
+// and to exclude, or nil when both are empty.
+func newTypeFilter(types, exclude string, nested bool) *microdata.TypeFilter {
+	if types == "" && exclude == "" {
+		return nil
+	}
+	return &microdata.TypeFilter{
+		Types:   splitList(types),
+		Exclude: splitList(exclude),
+		Nested:  nested,
+	}
+}
+
+// splitList returns the non-empty elements of the comma separated list.
+func splitList(s string) []string {
+	var list []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// parseSource returns the microdata of the given source. Sources starting
+// with http:// or https:// are fetched, other sources are file paths or
+// file:// URLs read from the local file system. See parseDocument for the
+// base URL and the content type of files.
+func parseSource(src string, opts *sourceOptions) (*microdata.Microdata, error) {
+	if isURL(src) {
+		if opts.cache != nil {
+			return opts.cache.ParseURL(src, opts.parseOptions()...)
+		}
+		return microdata.ParseURL(src, opts.parseOptions()...)
+	}
+
+	path := src
+	if strings.HasPrefix(src, "file:") {
+		u, err := url.Parse(src)
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.FromSlash(u.Path)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	docURL := &url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}
+	return parseDocument(f, docURL, opts)
+}
+
+// parseDocument returns the microdata of the document in r, located at the
+// given document URL. URLs are resolved against the base URL of the options
+// or, when it is empty, against the canonical URL from a link element or the
+// document URL. A base element in the document takes precedence over both.
+//
+// When the content type of the options has no charset, the charset is
+// detected from the byte order mark or the meta elements of the document,
+// falling back to UTF-8 for valid UTF-8 and windows-1252 otherwise. The
+// charset of the options overrides all but the byte order mark.
+func parseDocument(r io.Reader, docURL *url.URL, opts *sourceOptions) (*microdata.Microdata, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := opts.contentType
+	if contentType == "" {
+		contentType = "text/html"
+	}
+
+	u := docURL
+	switch {
+	case opts.baseURL != "":
+		if u, err = url.Parse(opts.baseURL); err != nil {
+			return nil, err
+		}
+	default:
+		if c, ok := canonicalURL(b, contentType); ok {
+			if cu, err := docURL.Parse(c); err == nil {
+				u = cu
+			}
+		}
+	}
+
+	return microdata.ParseHTML(bytes.NewReader(b), contentType, u, opts.parseOptions()...)
+}
+
+// canonicalURL returns the href of the first link element with a canonical
+// link type in the document.
+func canonicalURL(b []byte, contentType string) (string, bool) {
+	r, err := charset.NewReader(bytes.NewReader(b), contentType)
+	if err != nil {
+		return "", false
+	}
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", false
+	}
+
+	var href string
+	var found bool
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if found {
+			return
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Link {
+			var rel, h string
+			var hasHref bool
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "rel":
+					rel = attr.Val
+				case "href":
+					h, hasHref = attr.Val, true
+				}
+			}
+			for _, t := range strings.Fields(rel) {
+				if strings.EqualFold(t, "canonical") && hasHref {
+					href, found = h, true
+					return
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return href, found
+}
@@ -0,0 +1,97 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// linkHTML is a product with a relative URL, optionally preceded by head
+// elements.
+func linkHTML(head string) string {
+	return `<html><head>` + head + `</head><body>
+<div itemscope itemtype="http://schema.org/Product"><a itemprop="url" href="anvil">Anvil</a></div>
+</body></html>`
+}
+
+func TestParseSource(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"plain.html":     linkHTML(""),
+		"canonical.html": linkHTML(`<link rel="alternate canonical" href="http://shop.example.com/products/">`),
+		"base.html":      linkHTML(`<base href="http://cdn.example.com/p/"><link rel="canonical" href="http://shop.example.com/products/">`),
+	})
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileURL := (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+
+	var testTable = []struct {
+		src      string
+		baseURL  string
+		expected string
+	}{
+		{filepath.Join(dir, "plain.html"), "", fileURL + "/anvil"},
+		{fileURL + "/plain.html", "", fileURL + "/anvil"},
+		{filepath.Join(dir, "canonical.html"), "", "http://shop.example.com/products/anvil"},
+		{fileURL + "/canonical.html", "", "http://shop.example.com/products/anvil"},
+		{filepath.Join(dir, "canonical.html"), "http://example.com/store/", "http://example.com/store/anvil"},
+		{filepath.Join(dir, "base.html"), "", "http://cdn.example.com/p/anvil"},
+		{filepath.Join(dir, "base.html"), "http://example.com/store/", "http://cdn.example.com/p/anvil"},
+	}
+
+	for _, test := range testTable {
+		data, err := parseSource(test.src, &sourceOptions{baseURL: test.baseURL})
+		if err != nil {
+			t.Fatalf("%s: %v", test.src, err)
+		}
+		if len(data.Items) != 1 {
+			t.Fatalf("%s: Result should have been 1 item, but it was %d", test.src, len(data.Items))
+		}
+		if result := data.Items[0].Properties["url"][0]; result != test.expected {
+			t.Errorf("%s %s: Result should have been \"%s\", but it was \"%v\"", test.src, test.baseURL, test.expected, result)
+		}
+	}
+
+	if _, err := parseSource(filepath.Join(dir, "missing.html"), &sourceOptions{}); err == nil {
+		t.Error("Result should have been an error, but it was nil")
+	}
+}
+
+func TestParseDocumentCharset(t *testing.T) {
+	u, _ := url.Parse("http://example.com/")
+	doc := "<meta charset=\"windows-1252\"><div itemscope><span itemprop=\"name\">Caf\xe9</span></div>"
+
+	data, err := parseDocument(strings.NewReader(doc), u, &sourceOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := data.Items[0].Properties["name"][0]; result != "Café" {
+		t.Errorf("Result should have been \"Café\", but it was \"%v\"", result)
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	var testTable = []struct {
+		html     string
+		expected string
+		found    bool
+	}{
+		{`<link rel="canonical" href="http://example.com/a">`, "http://example.com/a", true},
+		{`<link rel="Canonical" href="/b"><link rel="canonical" href="/c">`, "/b", true},
+		{`<link rel="canonical"><link rel="canonical" href="/d">`, "/d", true},
+		{`<link rel="alternate" href="/e">`, "", false},
+	}
+
+	for _, test := range testTable {
+		href, found := canonicalURL([]byte(test.html), "text/html")
+		if href != test.expected || found != test.found {
+			t.Errorf("%s: Result should have been \"%s\" %t, but it was \"%s\" %t", test.html, test.expected, test.found, href, found)
+		}
+	}
+}
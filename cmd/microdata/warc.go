@@ -0,0 +1,68 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/namsral/microdata/warc"
+)
+
+// warcMain runs the warc subcommand with the given arguments and returns the
+// exit status.
+func warcMain(args []string) int {
+	fs := flag.NewFlagSet("warc", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s warc file ...:\n", os.Args[0])
+		fs.PrintDefaults()
+		fmt.Fprint(os.Stderr, "\nExtract the HTML Microdata from the HTML responses archived in WARC files, gzipped or not.")
+		fmt.Fprint(os.Stderr, " Writes one JSON record per line with the target URI of the response, its items or its error.\n")
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	status := 0
+	enc := json.NewEncoder(os.Stdout)
+	for _, path := range fs.Args() {
+		if err := extractWARC(enc, path); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// extractWARC writes a record for each HTML response in the WARC file.
+func extractWARC(enc *json.Encoder, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	e, err := warc.NewExtractor(f)
+	if err != nil {
+		return err
+	}
+	for e.Next() {
+		result := e.Result()
+		rec := record{Source: result.URI}
+		if result.Err != nil {
+			rec.Error = result.Err.Error()
+		} else {
+			rec.Encoding = result.Data.Encoding
+			rec.Items = result.Data.Items
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return e.Err()
+}
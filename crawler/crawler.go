@@ -0,0 +1,555 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+/*
+
+	Package crawler implements a polite web crawler which extracts the HTML
+	microdata of the pages it visits.
+
+	Usage:
+
+	Crawl the pages reachable from a seed URL on the same host.
+		c := &crawler.Crawler{Delay: time.Second}
+		for result := range c.Crawl(ctx, "http://example.com/") {
+			if result.Err != nil {
+				continue
+			}
+			items := result.Data.Items
+		}
+
+	Crawl the pages listed in a sitemap.
+		results := c.CrawlSitemap(ctx, "http://example.com/sitemap.xml", time.Time{})
+*/
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/namsral/microdata"
+	"github.com/namsral/microdata/sitemap"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// DefaultUserAgent is the user agent of a crawler without a UserAgent.
+const DefaultUserAgent = "microdata (+https://github.com/namsral/microdata)"
+
+// DefaultMaxBody is the maximum size of a page of a crawler without a MaxBody.
+const DefaultMaxBody = 10 << 20
+
+// ErrDisallowed is the error of the results for URLs which the robots.txt file
+// of their host disallows.
+var ErrDisallowed = errors.New("crawler: disallowed by robots.txt")
+
+// Result is the outcome of visiting a URL. Data is nil when Err is set.
+type Result struct {
+	URL  string
+	Data *microdata.Microdata
+	Err  error
+}
+
+// Crawler visits the pages reachable from its seeds on the hosts of the seeds.
+// The zero value is ready to use.
+type Crawler struct {
+	// Client is the HTTP client used for requests. The default is
+	// http.DefaultClient.
+	Client *http.Client
+
+	// UserAgent is sent with every request. Its first token selects the group
+	// of a robots.txt file. The default is DefaultUserAgent.
+	UserAgent string
+
+	// Concurrency is the number of workers visiting the queued URLs, the
+	// maximum number of concurrent requests. The default is 4.
+	Concurrency int
+
+	// HostConcurrency is the maximum number of concurrent requests per host.
+	// The default is 1.
+	HostConcurrency int
+
+	// Delay is the minimum delay between two requests to the same host. The
+	// Crawl-delay of a robots.txt file takes precedence when it's longer.
+	Delay time.Duration
+
+	// MaxPages is the maximum number of URLs to visit, 0 means no limit.
+	MaxPages int
+
+	// MaxDepth is the maximum number of links followed from a seed, 0 means
+	// no limit.
+	MaxDepth int
+
+	// MaxBody is the maximum size in bytes of a page. Larger pages fail. The
+	// default is DefaultMaxBody.
+	MaxBody int64
+
+	// IgnoreRobots disables the robots.txt rules.
+	IgnoreRobots bool
+}
+
+// Crawl visits the seed URLs and the pages they link to on the same hosts,
+// and streams the results. Every URL is visited once, ignoring fragments. The
+// channel is closed when the crawl is done or the context is canceled. Pages
+// which aren't HTML are skipped.
+func (c *Crawler) Crawl(ctx context.Context, seeds ...string) <-chan Result {
+	results := make(chan Result)
+	cr := c.newCrawl(ctx, results)
+
+	go func() {
+		for _, seed := range seeds {
+			u, err := url.Parse(seed)
+			if err != nil {
+				cr.send(Result{URL: seed, Err: err})
+				continue
+			}
+			cr.hosts[u.Host] = true
+		}
+		for _, seed := range seeds {
+			if u, err := url.Parse(seed); err == nil {
+				cr.enqueue(u, 0)
+			}
+		}
+		cr.run()
+		close(results)
+	}()
+	return results
+}
+
+// CrawlSitemap visits the URLs of the sitemap, or of the sitemaps of a sitemap
+// index, as seeds, see Crawl. Only the URLs modified after since are visited
+// unless since is the zero time.
+func (c *Crawler) CrawlSitemap(ctx context.Context, sitemapURL string, since time.Time) <-chan Result {
+	r := &sitemap.Reader{Client: c.Client, UserAgent: c.userAgent(), Since: since}
+	urls, err := r.Read(ctx, sitemapURL)
+	if err != nil {
+		results := make(chan Result, 1)
+		results <- Result{URL: sitemapURL, Err: err}
+		close(results)
+		return results
+	}
+
+	seeds := make([]string, 0, len(urls))
+	for _, u := range urls {
+		seeds = append(seeds, u.Loc)
+	}
+	return c.Crawl(ctx, seeds...)
+}
+
+// get sends a GET request with the user agent of the crawler.
+func (c *Crawler) get(ctx context.Context, client *http.Client, rawurl string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", c.userAgent())
+	return client.Do(req)
+}
+
+// client returns the HTTP client of the crawler.
+func (c *Crawler) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return http.DefaultClient
+}
+
+// userAgent returns the user agent of the crawler.
+func (c *Crawler) userAgent() string {
+	if c.UserAgent != "" {
+		return c.UserAgent
+	}
+	return DefaultUserAgent
+}
+
+// maxBody returns the maximum size of a page.
+func (c *Crawler) maxBody() int64 {
+	if c.MaxBody > 0 {
+		return c.MaxBody
+	}
+	return DefaultMaxBody
+}
+
+// crawl holds the state of a single crawl.
+type crawl struct {
+	c       *Crawler
+	ctx     context.Context
+	results chan<- Result
+	workers int
+
+	// client is the client of the crawler checking the redirects of the
+	// pages.
+	client *http.Client
+
+	// hosts holds the hosts of the seeds, it's read-only once the seeds are
+	// enqueued.
+	hosts map[string]bool
+
+	mu        sync.Mutex
+	queued    *sync.Cond
+	queue     []task
+	active    int
+	seen      map[string]bool
+	visits    int
+	hostState map[string]*host
+}
+
+// task is a queued URL and the number of links followed from its seed.
+type task struct {
+	u     *url.URL
+	depth int
+}
+
+// host holds the state of a single host.
+type host struct {
+	once   sync.Once
+	robots *robots
+	slots  chan struct{}
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// newCrawl returns a crawl sending its results to the given channel.
+func (c *Crawler) newCrawl(ctx context.Context, results chan<- Result) *crawl {
+	n := c.Concurrency
+	if n <= 0 {
+		n = 4
+	}
+	cr := &crawl{
+		c:         c,
+		ctx:       ctx,
+		results:   results,
+		workers:   n,
+		hosts:     make(map[string]bool),
+		seen:      make(map[string]bool),
+		hostState: make(map[string]*host),
+	}
+	cr.queued = sync.NewCond(&cr.mu)
+
+	client := *c.client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := cr.checkRedirect(req); err != nil {
+			return err
+		}
+		if c.client().CheckRedirect != nil {
+			return c.client().CheckRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("crawler: stopped after 10 redirects")
+		}
+		return nil
+	}
+	cr.client = &client
+	return cr
+}
+
+// send sends the result unless the context is canceled.
+func (cr *crawl) send(r Result) {
+	select {
+	case cr.results <- r:
+	case <-cr.ctx.Done():
+	}
+}
+
+// enqueue queues the URL when it wasn't seen before and the page limit isn't
+// reached.
+func (cr *crawl) enqueue(u *url.URL, depth int) {
+	u.Fragment = ""
+	key := u.String()
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.seen[key] || (cr.c.MaxPages > 0 && cr.visits >= cr.c.MaxPages) {
+		return
+	}
+	cr.seen[key] = true
+	cr.visits++
+	cr.queue = append(cr.queue, task{u, depth})
+	cr.queued.Signal()
+}
+
+// run visits the queued URLs with the workers of the crawl. It returns when
+// the queue is empty and no worker is visiting a URL, or when the context is
+// canceled.
+func (cr *crawl) run() {
+	stop := context.AfterFunc(cr.ctx, func() {
+		cr.mu.Lock()
+		cr.queued.Broadcast()
+		cr.mu.Unlock()
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < cr.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				t, ok := cr.next()
+				if !ok {
+					return
+				}
+				cr.visit(t.u, t.depth)
+				cr.done()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// next returns the next queued URL, waiting while the queue is empty and other
+// workers are visiting URLs which may add links to it. It returns false when
+// the crawl is done.
+func (cr *crawl) next() (task, bool) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	for len(cr.queue) == 0 && cr.active > 0 && cr.ctx.Err() == nil {
+		cr.queued.Wait()
+	}
+	if len(cr.queue) == 0 || cr.ctx.Err() != nil {
+		return task{}, false
+	}
+	t := cr.queue[0]
+	cr.queue[0] = task{}
+	cr.queue = cr.queue[1:]
+	cr.active++
+	return t, true
+}
+
+// done marks the visit of a URL returned by next as done.
+func (cr *crawl) done() {
+	cr.mu.Lock()
+	cr.active--
+	if cr.active == 0 && len(cr.queue) == 0 {
+		// Wake the waiting workers to end the crawl.
+		cr.queued.Broadcast()
+	}
+	cr.mu.Unlock()
+}
+
+// checkRedirect refuses the redirects of pages to other hosts than the hosts
+// of the seeds and to URLs which the robots.txt file of their host disallows.
+func (cr *crawl) checkRedirect(req *http.Request) error {
+	u := req.URL
+	if !cr.hosts[u.Host] || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("crawler: redirect to %s outside the crawled hosts", u)
+	}
+	if !cr.host(u).robots.allowed(u.RequestURI()) {
+		return ErrDisallowed
+	}
+	return nil
+}
+
+// host returns the state of the named host, fetching its robots.txt file on
+// first use.
+func (cr *crawl) host(u *url.URL) *host {
+	cr.mu.Lock()
+	h, ok := cr.hostState[u.Host]
+	if !ok {
+		n := cr.c.HostConcurrency
+		if n <= 0 {
+			n = 1
+		}
+		h = &host{slots: make(chan struct{}, n)}
+		cr.hostState[u.Host] = h
+	}
+	cr.mu.Unlock()
+
+	h.once.Do(func() {
+		h.robots = allowAll
+		if !cr.c.IgnoreRobots {
+			h.robots = cr.fetchRobots(u)
+		}
+	})
+	return h
+}
+
+// fetchRobots returns the robots.txt rules of the host of the URL. Missing
+// files allow everything, server errors and unreachable hosts disallow
+// everything.
+func (cr *crawl) fetchRobots(u *url.URL) *robots {
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+	resp, err := cr.c.get(cr.ctx, cr.c.client(), robotsURL.String())
+	if err != nil {
+		return disallowAll
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		token := strings.FieldsFunc(cr.c.userAgent(), func(r rune) bool { return r == '/' || r == ' ' })
+		if len(token) == 0 {
+			return allowAll
+		}
+		return parseRobots(io.LimitReader(resp.Body, 500<<10), token[0])
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return allowAll
+	}
+	return disallowAll
+}
+
+// wait blocks until the next request to the host is allowed.
+func (h *host) wait(ctx context.Context, delay time.Duration) error {
+	if h.robots.delay > delay {
+		delay = h.robots.delay
+	}
+
+	h.mu.Lock()
+	now := time.Now()
+	at := h.next
+	if at.Before(now) {
+		at = now
+	}
+	h.next = at.Add(delay)
+	h.mu.Unlock()
+
+	t := time.NewTimer(at.Sub(now))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// visit fetches the page at the URL, sends its result and enqueues the links
+// to the hosts of the seeds.
+func (cr *crawl) visit(u *url.URL, depth int) {
+	if cr.ctx.Err() != nil {
+		return
+	}
+
+	h := cr.host(u)
+	if !h.robots.allowed(u.RequestURI()) {
+		cr.send(Result{URL: u.String(), Err: ErrDisallowed})
+		return
+	}
+
+	h.slots <- struct{}{}
+	err := h.wait(cr.ctx, cr.c.Delay)
+	var body []byte
+	var contentType string
+	var final *url.URL
+	if err == nil {
+		body, contentType, final, err = cr.fetch(u)
+	}
+	<-h.slots
+
+	if err != nil {
+		cr.send(Result{URL: u.String(), Err: err})
+		return
+	}
+	if body == nil {
+		// Not an HTML page.
+		return
+	}
+
+	if final.String() != u.String() {
+		cr.mu.Lock()
+		cr.seen[final.String()] = true
+		cr.mu.Unlock()
+	}
+
+	data, err := microdata.ParseHTML(bytes.NewReader(body), contentType, final)
+	cr.send(Result{URL: u.String(), Data: data, Err: err})
+
+	if cr.c.MaxDepth > 0 && depth >= cr.c.MaxDepth {
+		return
+	}
+	for _, link := range links(body, contentType, final) {
+		if cr.hosts[link.Host] && (link.Scheme == "http" || link.Scheme == "https") {
+			cr.enqueue(link, depth+1)
+		}
+	}
+}
+
+// fetch returns the body, the content type and the final URL of the HTML page
+// at the URL. The body is nil when the page isn't HTML. Redirects are checked
+// by checkRedirect.
+func (cr *crawl) fetch(u *url.URL) ([]byte, string, *url.URL, error) {
+	resp, err := cr.c.get(cr.ctx, cr.client, u.String())
+	if err != nil {
+		return nil, "", nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", nil, fmt.Errorf("crawler: fetching %s: %s", u, resp.Status)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); contentType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, "", nil, nil
+	}
+
+	max := cr.c.maxBody()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, max+1))
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if int64(len(body)) > max {
+		return nil, "", nil, fmt.Errorf("crawler: fetching %s: page is larger than %d bytes", u, max)
+	}
+	return body, contentType, resp.Request.URL, nil
+}
+
+// links returns the URLs of the a and area elements in the HTML document,
+// resolved against the base element of the document or the given base URL.
+func links(body []byte, contentType string, base *url.URL) []*url.URL {
+	r, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		return nil
+	}
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil
+	}
+
+	var hrefs []string
+	baseFound := false
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for _, attr := range n.Attr {
+				if attr.Key != "href" {
+					continue
+				}
+				switch n.DataAtom {
+				case atom.Base:
+					if !baseFound {
+						baseFound = true
+						if u, err := base.Parse(attr.Val); err == nil {
+							base = u
+						}
+					}
+				case atom.A, atom.Area:
+					hrefs = append(hrefs, attr.Val)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	var urls []*url.URL
+	for _, href := range hrefs {
+		if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
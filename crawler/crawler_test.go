@@ -0,0 +1,250 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newSite returns a test site of linked pages with microdata and a
+// robots.txt file which disallows /private.
+func newSite(t *testing.T) (*httptest.Server, *sync.Map) {
+	hits := &sync.Map{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := hits.LoadOrStore(r.URL.Path, new(int))
+		*n.(*int)++
+
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/a">A</a> <a href="/b#top">B</a> <a href="/private">P</a> <a href="http://other.example.com/">O</a> <a href="/image.png">I</a>`)
+		case "/a":
+			fmt.Fprint(w, `<div itemscope itemtype="http://schema.org/Product"><span itemprop="name">A</span></div><a href="/">Home</a> <a href="/b">B</a>`)
+		case "/b":
+			fmt.Fprint(w, `<div itemscope itemtype="http://schema.org/Product"><span itemprop="name">B</span></div><a href="/c">C</a>`)
+		case "/c":
+			fmt.Fprint(w, `<p>C</p>`)
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>http://%s/a</loc></url>
+  <url><loc>http://%s/c</loc></url>
+</urlset>`, r.Host, r.Host)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	return httptest.NewServer(mux), hits
+}
+
+// collect returns the results by path.
+func collect(t *testing.T, ts *httptest.Server, results <-chan Result) map[string]Result {
+	m := make(map[string]Result)
+	for r := range results {
+		path := r.URL[len(ts.URL):]
+		if _, ok := m[path]; ok {
+			t.Errorf("Result for %s should have been sent once", path)
+		}
+		m[path] = r
+	}
+	return m
+}
+
+func TestCrawl(t *testing.T) {
+	ts, hits := newSite(t)
+	defer ts.Close()
+
+	c := &Crawler{Concurrency: 2}
+	results := collect(t, ts, c.Crawl(context.Background(), ts.URL+"/"))
+
+	var paths []string
+	for path := range results {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	expected := fmt.Sprint([]string{"/", "/a", "/b", "/c", "/private"})
+	if result := fmt.Sprint(paths); result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+
+	if results["/private"].Err != ErrDisallowed {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", ErrDisallowed, results["/private"].Err)
+	}
+	if _, ok := hits.Load("/private"); ok {
+		t.Error("Disallowed page should not have been fetched")
+	}
+
+	result := results["/b"].Data.Items[0].Properties["name"][0].(string)
+	if result != "B" {
+		t.Errorf("Result should have been \"B\", but it was \"%s\"", result)
+	}
+
+	hits.Range(func(k, v interface{}) bool {
+		if n := *v.(*int); n != 1 {
+			t.Errorf("%s should have been fetched once, but it was fetched %d times", k, n)
+		}
+		return true
+	})
+}
+
+func TestCrawlLimits(t *testing.T) {
+	ts, _ := newSite(t)
+	defer ts.Close()
+
+	c := &Crawler{MaxDepth: 1}
+	results := collect(t, ts, c.Crawl(context.Background(), ts.URL+"/"))
+	if _, ok := results["/c"]; ok {
+		t.Error("Page beyond the maximum depth should not have been visited")
+	}
+
+	c = &Crawler{MaxPages: 2}
+	results = collect(t, ts, c.Crawl(context.Background(), ts.URL+"/"))
+	if len(results) != 2 {
+		t.Errorf("Result should have been 2 pages, but it was %d", len(results))
+	}
+
+	c = &Crawler{MaxBody: 100}
+	results = collect(t, ts, c.Crawl(context.Background(), ts.URL+"/a", ts.URL+"/c"))
+	if r := results["/a"]; r.Err == nil || !strings.Contains(r.Err.Error(), "larger than 100 bytes") {
+		t.Errorf("Result should have been a page size error, but it was %v", r.Err)
+	}
+	if r, ok := results["/c"]; !ok || r.Err != nil {
+		t.Errorf("Result should have been no error, but it was %v", r.Err)
+	}
+}
+
+func TestCrawlDelay(t *testing.T) {
+	ts, _ := newSite(t)
+	defer ts.Close()
+
+	delay := 20 * time.Millisecond
+	c := &Crawler{Concurrency: 4, HostConcurrency: 4, Delay: delay, MaxDepth: 1}
+	start := time.Now()
+	results := collect(t, ts, c.Crawl(context.Background(), ts.URL+"/"))
+
+	// The pages /, /a and /b are fetched, /private is disallowed.
+	if len(results) != 4 {
+		t.Fatalf("Result should have been 4 pages, but it was %d", len(results))
+	}
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Errorf("Crawl should have taken at least %s, but it took %s", 2*delay, elapsed)
+	}
+}
+
+func TestCrawlSitemap(t *testing.T) {
+	ts, _ := newSite(t)
+	defer ts.Close()
+
+	c := &Crawler{MaxDepth: 1}
+	results := collect(t, ts, c.CrawlSitemap(context.Background(), ts.URL+"/sitemap.xml", time.Time{}))
+
+	for _, path := range []string{"/a", "/c", "/b"} {
+		if _, ok := results[path]; !ok {
+			t.Errorf("Result should have included %s", path)
+		}
+	}
+}
+
+func TestCrawlCancel(t *testing.T) {
+	ts, _ := newSite(t)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Crawler{Delay: time.Hour}
+	results := c.Crawl(ctx, ts.URL+"/")
+	<-results
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for range results {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Results should have been closed after canceling the context")
+	}
+}
+
+func TestCrawlRedirect(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<div itemscope><span itemprop="name">Other</span></div>`)
+	}))
+	defer other.Close()
+
+	ts, hits := newSite(t)
+	defer ts.Close()
+	mux := ts.Config.Handler.(*http.ServeMux)
+	mux.HandleFunc("/to-other", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL+"/", http.StatusFound)
+	})
+	mux.HandleFunc("/to-private", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/private", http.StatusFound)
+	})
+	mux.HandleFunc("/to-a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/a", http.StatusFound)
+	})
+
+	c := &Crawler{MaxDepth: 1}
+	results := collect(t, ts, c.Crawl(context.Background(), ts.URL+"/to-other", ts.URL+"/to-private", ts.URL+"/to-a"))
+
+	if err := results["/to-other"].Err; err == nil || !strings.Contains(err.Error(), "outside the crawled hosts") {
+		t.Errorf("Result should have been a redirect outside the crawled hosts, but it was \"%v\"", err)
+	}
+	if err := results["/to-private"].Err; !errors.Is(err, ErrDisallowed) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", ErrDisallowed, err)
+	}
+	if _, ok := hits.Load("/private"); ok {
+		t.Error("Disallowed page should not have been fetched")
+	}
+	if data := results["/to-a"].Data; data == nil || len(data.Items) != 1 {
+		t.Errorf("Result should have been the items of /a, but it was \"%v\"", results["/to-a"])
+	}
+}
+
+func TestCrawlWorkers(t *testing.T) {
+	const pages = 500
+	var mu sync.Mutex
+	maxGoroutines := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if n := runtime.NumGoroutine(); n > maxGoroutines {
+			maxGoroutines = n
+		}
+		mu.Unlock()
+		if r.URL.Path == "/" {
+			for i := 0; i < pages; i++ {
+				fmt.Fprintf(w, `<a href="/p%d">%d</a>`, i, i)
+			}
+		}
+	}))
+	defer ts.Close()
+
+	c := &Crawler{Concurrency: 2, IgnoreRobots: true}
+	results := collect(t, ts, c.Crawl(context.Background(), ts.URL+"/"))
+	if len(results) != pages+1 {
+		t.Errorf("Result should have been %d pages, but it was %d", pages+1, len(results))
+	}
+	if maxGoroutines > pages/10 {
+		t.Errorf("Result should have been at most %d goroutines, but it was %d", pages/10, maxGoroutines)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package crawler
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robots holds the rules of a robots.txt file for a single user agent.
+type robots struct {
+	rules []rule
+	delay time.Duration
+}
+
+// rule is an allow or disallow rule of a robots.txt file.
+type rule struct {
+	allow   bool
+	pattern string
+}
+
+// allowAll and disallowAll are the rules used when a robots.txt file is
+// unavailable or unreachable.
+var (
+	allowAll    = &robots{}
+	disallowAll = &robots{rules: []rule{{allow: false, pattern: "/"}}}
+)
+
+// parseRobots parses the robots.txt file in r and returns the rules of the
+// group matching the given product token, or of the "*" group when no group
+// matches, as specified by RFC 9309. The Crawl-delay extension is supported.
+func parseRobots(r io.Reader, token string) *robots {
+	type group struct {
+		agents []string
+		robots robots
+	}
+	var groups []*group
+	var current *group
+	inRules := false
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if current == nil || inRules {
+				current = &group{}
+				groups = append(groups, current)
+				inRules = false
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			if value != "" {
+				current.robots.rules = append(current.robots.rules, rule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			if f, err := strconv.ParseFloat(value, 64); err == nil && f > 0 {
+				current.robots.delay = time.Duration(f * float64(time.Second))
+			}
+		}
+	}
+
+	// Groups for the same user agent are combined.
+	token = strings.ToLower(token)
+	var match, wildcard *robots
+	merge := func(dst **robots, src *robots) {
+		if *dst == nil {
+			*dst = &robots{}
+		}
+		(*dst).rules = append((*dst).rules, src.rules...)
+		if src.delay > (*dst).delay {
+			(*dst).delay = src.delay
+		}
+	}
+	for _, g := range groups {
+		var isMatch, isWildcard bool
+		for _, agent := range g.agents {
+			isMatch = isMatch || agent == token
+			isWildcard = isWildcard || agent == "*"
+		}
+		switch {
+		case isMatch:
+			merge(&match, &g.robots)
+		case isWildcard:
+			merge(&wildcard, &g.robots)
+		}
+	}
+
+	switch {
+	case match != nil:
+		return match
+	case wildcard != nil:
+		return wildcard
+	}
+	return allowAll
+}
+
+// allowed reports whether the path, including its query, may be fetched. The
+// most specific matching rule wins, allow rules win ties.
+func (r *robots) allowed(path string) bool {
+	allow, length := true, -1
+	for _, rule := range r.rules {
+		if !matchPattern(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > length || (n == length && rule.allow) {
+			allow, length = rule.allow, n
+		}
+	}
+	return allow
+}
+
+// matchPattern reports whether the path starts with the pattern. In a pattern,
+// "*" matches any sequence of characters and a trailing "$" anchors the
+// pattern at the end of the path.
+func matchPattern(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "$") {
+		return matchGlob(strings.TrimSuffix(pattern, "$"), path)
+	}
+	return matchGlob(pattern+"*", path)
+}
+
+// matchGlob reports whether the whole path matches the pattern, where "*"
+// matches any sequence of characters. It doesn't backtrack recursively: on a
+// mismatch only the last "*" is extended, which takes O(len(pattern) *
+// len(path)) time in the worst case.
+func matchGlob(pattern, path string) bool {
+	p, s := 0, 0
+	star, next := -1, 0
+	for s < len(path) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, next = p, s
+			p++
+		case p < len(pattern) && pattern[p] == path[s]:
+			p++
+			s++
+		case star >= 0:
+			// Let the last "*" match one more character.
+			next++
+			p, s = star+1, next
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
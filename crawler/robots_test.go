@@ -0,0 +1,103 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package crawler
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRobots(t *testing.T) {
+	txt := `
+# Rules for everyone
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+
+User-agent: microdata
+User-agent: otherbot
+Disallow: /admin
+Crawl-delay: 0.5
+
+User-agent: microdata
+Disallow: /tmp/
+`
+
+	var testTable = []struct {
+		token    string
+		path     string
+		expected bool
+	}{
+		{"microdata", "/admin/users", false},
+		{"microdata", "/tmp/file", false},
+		{"microdata", "/private", true},
+		{"Microdata", "/admin", false},
+		{"somebot", "/private/data", false},
+		{"somebot", "/private/public/data", true},
+		{"somebot", "/docs/report.pdf", false},
+		{"somebot", "/docs/report.pdf?download=1", true},
+		{"somebot", "/admin", true},
+	}
+
+	for _, test := range testTable {
+		r := parseRobots(strings.NewReader(txt), test.token)
+		if result := r.allowed(test.path); result != test.expected {
+			t.Errorf("%s %s: Result should have been \"%t\", but it was \"%t\"", test.token, test.path, test.expected, result)
+		}
+	}
+
+	r := parseRobots(strings.NewReader(txt), "microdata")
+	if expected := 500 * time.Millisecond; r.delay != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, r.delay)
+	}
+}
+
+func TestParseRobotsEmpty(t *testing.T) {
+	r := parseRobots(strings.NewReader("User-agent: *\nDisallow:\n"), "microdata")
+	if !r.allowed("/anything") {
+		t.Error("Result should have been allowed")
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	var testTable = []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"/private", "/private/data", true},
+		{"/private", "/public", false},
+		{"/*.pdf$", "/docs/report.pdf", true},
+		{"/*.pdf$", "/docs/report.pdf?x", false},
+		{"/*/data", "/a/b/data/c", true},
+		{"/a*b*c$", "/aXbYbZc", true},
+		{"/a*b*c$", "/aXbYbZ", false},
+		{"/**x", "/x", true},
+		{"*", "", true},
+		{"", "/", true},
+		{"/$", "/", true},
+		{"/$", "/a", false},
+	}
+
+	for _, test := range testTable {
+		if result := matchPattern(test.pattern, test.path); result != test.expected {
+			t.Errorf("%s %s: Result should have been \"%t\", but it was \"%t\"", test.pattern, test.path, test.expected, result)
+		}
+	}
+}
+
+func TestMatchPatternPathological(t *testing.T) {
+	pattern := "/*a*a*a*a*a*a*a*a*a*a*a*a*a*a*a*a*a*a*a*a*b"
+	path := "/" + strings.Repeat("a", 1000)
+
+	start := time.Now()
+	if matchPattern(pattern, path) {
+		t.Error("Result should have been no match")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Matching should have taken less than a second, but it took %s", elapsed)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ItemDiff describes an item which was added, removed or changed between two
+// documents. Old is nil for added items, New is nil for removed items and
+// both are set for changed items.
+type ItemDiff struct {
+	Key         string       `json:"key"`
+	Old         *Item        `json:"old,omitempty"`
+	New         *Item        `json:"new,omitempty"`
+	Differences []Difference `json:"differences,omitempty"`
+}
+
+// String returns a description of the item diff, one line for the item
+// followed by one indented line per difference.
+func (d ItemDiff) String() string {
+	switch {
+	case d.Old == nil:
+		return fmt.Sprintf("+ %s %q", d.Key, d.New.Types)
+	case d.New == nil:
+		return fmt.Sprintf("- %s %q", d.Key, d.Old.Types)
+	}
+
+	lines := []string{fmt.Sprintf("~ %s %q", d.Key, d.Old.Types)}
+	for _, diff := range d.Differences {
+		lines = append(lines, "    "+diff.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Fingerprint returns a structural fingerprint of the item, derived from its
+// types and property names but not from its property values. Items with the
+// same structure share the same fingerprint.
+func (i *Item) Fingerprint() string {
+	names := make([]string, 0, len(i.Properties))
+	for name := range i.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%q\n%q", canonicalTypes(i.Types), names)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// key returns the key which identifies the item in a diff, its itemid or its
+// fingerprint when it has none.
+func (i *Item) key() string {
+	if i.ID != "" {
+		return i.ID
+	}
+	return i.Fingerprint()
+}
+
+// Diff returns the differences between the top-level items of the old and the
+// new document. Items are matched by itemid. Items without an itemid are
+// matched by their fingerprint in document order, then by their types and
+// finally by their property names, which reports a changed type or an added
+// property as a change instead of a removal and an addition.
+//
+// The result lists the removed and changed items in the order of the old
+// document, followed by the added items in the order of the new document.
+// Unchanged items are omitted.
+func Diff(old, new *Microdata) []ItemDiff {
+	pairs := make(map[*Item]*Item)
+	matched := make(map[*Item]bool)
+
+	match := func(key func(*Item) string) {
+		candidates := make(map[string][]*Item)
+		for _, item := range new.Items {
+			if !matched[item] {
+				k := key(item)
+				candidates[k] = append(candidates[k], item)
+			}
+		}
+		for _, item := range old.Items {
+			if _, ok := pairs[item]; ok {
+				continue
+			}
+			k := key(item)
+			if list := candidates[k]; len(list) > 0 {
+				pairs[item] = list[0]
+				matched[list[0]] = true
+				candidates[k] = list[1:]
+			}
+		}
+	}
+
+	match(func(i *Item) string {
+		if i.ID == "" {
+			// Items without an itemid never match by key.
+			return fmt.Sprintf("%p", i)
+		}
+		return i.ID
+	})
+	match(func(i *Item) string { return i.ID + "\n" + i.Fingerprint() })
+	match(func(i *Item) string { return i.ID + "\n" + strings.Join(canonicalTypes(i.Types), " ") })
+	match(func(i *Item) string {
+		if i.ID != "" {
+			return fmt.Sprintf("%p", i)
+		}
+		names := make([]string, 0, len(i.Properties))
+		for name := range i.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return strings.Join(names, " ")
+	})
+
+	var diffs []ItemDiff
+	for _, item := range old.Items {
+		other, ok := pairs[item]
+		if !ok {
+			diffs = append(diffs, ItemDiff{Key: item.key(), Old: item})
+			continue
+		}
+		if d := item.Diff(other); len(d) > 0 {
+			diffs = append(diffs, ItemDiff{Key: item.key(), Old: item, New: other, Differences: d})
+		}
+	}
+	for _, item := range new.Items {
+		if !matched[item] {
+			diffs = append(diffs, ItemDiff{Key: item.key(), New: item})
+		}
+	}
+	return diffs
+}
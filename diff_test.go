@@ -0,0 +1,94 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"testing"
+)
+
+func TestDiffDocuments(t *testing.T) {
+	a := ParseData(`
+		<div itemscope itemtype="http://schema.org/Product" itemid="urn:sku:1">
+			<span itemprop="name">Kettle</span>
+			<span itemprop="price">19.95</span>
+		</div>
+		<div itemscope itemtype="http://schema.org/Product" itemid="urn:sku:2">
+			<span itemprop="name">Toaster</span>
+		</div>
+		<div itemscope itemtype="http://schema.org/BreadcrumbList">
+			<span itemprop="name">Home</span>
+		</div>
+		<div itemscope itemtype="http://schema.org/Rating">
+			<span itemprop="ratingValue">4</span>
+		</div>`, t)
+	b := ParseData(`
+		<div itemscope itemtype="http://schema.org/Product" itemid="urn:sku:1">
+			<span itemprop="name">Kettle</span>
+			<span itemprop="price">17.95</span>
+		</div>
+		<div itemscope itemtype="http://schema.org/Product" itemid="urn:sku:3">
+			<span itemprop="name">Blender</span>
+		</div>
+		<div itemscope itemtype="http://schema.org/BreadcrumbList">
+			<span itemprop="name">Home</span>
+		</div>
+		<div itemscope itemtype="http://schema.org/AggregateRating">
+			<span itemprop="ratingValue">4</span>
+		</div>`, t)
+
+	var testTable = []string{
+		"~ urn:sku:1 [\"http://schema.org/Product\"]\n" +
+			`    price[0]: "19.95" -> "17.95"`,
+		`- urn:sku:2 ["http://schema.org/Product"]`,
+		"~ " + a.Items[3].Fingerprint() + " [\"http://schema.org/Rating\"]\n" +
+			`    type: ["http://schema.org/Rating"] -> ["http://schema.org/AggregateRating"]`,
+		`+ urn:sku:3 ["http://schema.org/Product"]`,
+	}
+
+	diffs := Diff(a, b)
+	if len(diffs) != len(testTable) {
+		t.Fatalf("Result should have been %d item diffs, but it was %d: %v", len(testTable), len(diffs), diffs)
+	}
+	for i, expected := range testTable {
+		if result := diffs[i].String(); result != expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+		}
+	}
+}
+
+func TestDiffRepeatedItems(t *testing.T) {
+	a := ParseData(gallerySnippet, t)
+	b := ParseData(gallerySnippet, t)
+	b.Items[1].Properties["title"][0] = "The red mailbox."
+
+	diffs := Diff(a, b)
+	if len(diffs) != 1 {
+		t.Fatalf("Result should have been 1 item diff, but it was %d: %v", len(diffs), diffs)
+	}
+	if diffs[0].Old != a.Items[1] || diffs[0].New != b.Items[1] {
+		t.Errorf("Result should have matched the second items, but it was %v", diffs[0])
+	}
+}
+
+func TestDiffIdentical(t *testing.T) {
+	a := ParseData(blogSnippet, t)
+	b := ParseData(blogSnippet, t)
+
+	if diffs := Diff(a, b); len(diffs) != 0 {
+		t.Errorf("Result should have been no item diffs, but it was %v", diffs)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := ParseData(gallerySnippet, t)
+
+	if a.Items[0].Fingerprint() != a.Items[1].Fingerprint() {
+		t.Error("Items with the same structure should have had the same fingerprint")
+	}
+
+	a.Items[1].Properties["caption"] = ValueList{"A mailbox"}
+	if a.Items[0].Fingerprint() == a.Items[1].Fingerprint() {
+		t.Error("Items with a different structure should have had a different fingerprint")
+	}
+}
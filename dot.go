@@ -0,0 +1,123 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxDOTLabel is the maximum number of characters of the label of a literal
+// value in the DOT graph. Longer values are truncated.
+const maxDOTLabel = 60
+
+// dotNode is an item or a text value in the DOT graph.
+type dotNode struct {
+	id    string
+	label string
+	value bool
+}
+
+// dotEdge is a property of an item in the DOT graph.
+type dotEdge struct {
+	from, to string
+	label    string
+}
+
+// WriteDOT writes the items as a graph in the GraphViz DOT language. Items
+// are boxes labelled with their types and itemid, text values are plain text
+// nodes and properties are edges labelled with their names. An item held by
+// more than one item, e.g. read through the itemref attributes of several
+// items, is drawn once and highlighted.
+func (m *Microdata) WriteDOT(w io.Writer) error {
+	var nodes []dotNode
+	var edges []dotEdge
+	ids := make(map[*Item]string)
+	parents := make(map[string]map[string]bool)
+	values := 0
+
+	var visit func(item *Item) string
+	visit = func(item *Item) string {
+		if id, ok := ids[item]; ok {
+			return id
+		}
+		id := fmt.Sprintf("item%d", len(ids))
+		ids[item] = id
+		nodes = append(nodes, dotNode{id: id, label: dotItemLabel(item)})
+
+		for _, name := range item.PropertyNames() {
+			for _, v := range item.Properties[name] {
+				if sub, ok := v.(*Item); ok {
+					subID := visit(sub)
+					if parents[subID] == nil {
+						parents[subID] = make(map[string]bool)
+					}
+					parents[subID][id] = true
+					edges = append(edges, dotEdge{id, subID, name})
+					continue
+				}
+
+				valueID := fmt.Sprintf("value%d", values)
+				values++
+				nodes = append(nodes, dotNode{id: valueID, label: truncate(fmt.Sprint(v), maxDOTLabel), value: true})
+				edges = append(edges, dotEdge{id, valueID, name})
+			}
+		}
+		return id
+	}
+	for _, item := range m.Items {
+		visit(item)
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph microdata {\n")
+	bw.WriteString("\tnode [shape=box];\n")
+	for _, n := range nodes {
+		attrs := "label=" + dotString(n.label)
+		switch {
+		case n.value:
+			attrs += ", shape=plaintext"
+		case len(parents[n.id]) > 1:
+			attrs += ", color=red, penwidth=2"
+		}
+		fmt.Fprintf(bw, "\t%s [%s];\n", n.id, attrs)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(bw, "\t%s -> %s [label=%s];\n", e.from, e.to, dotString(e.label))
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// dotItemLabel returns the label of the item, its types on a line each and
+// its itemid.
+func dotItemLabel(item *Item) string {
+	lines := append([]string{}, item.Types...)
+	if len(lines) == 0 {
+		lines = append(lines, "(item)")
+	}
+	if item.ID != "" {
+		lines = append(lines, "<"+item.ID+">")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dotString returns s as a quoted DOT string. Line breaks become centered
+// line breaks of the label.
+func dotString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// truncate returns s with at most n characters, ending with an ellipsis when
+// it was truncated.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,67 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestWriteDOT(t *testing.T) {
+	html := `<div itemscope itemtype="http://schema.org/Product" itemid="urn:sku:1" itemref="seller">
+	<span itemprop="name">The "Anvil"</span>
+</div>
+<div itemscope itemtype="http://schema.org/Product" itemref="seller"><span itemprop="name">Hammer</span></div>
+<div id="seller" itemprop="seller" itemscope itemtype="http://schema.org/Organization"><span itemprop="name">ACME</span></div>`
+
+	u, _ := url.Parse("http://example.com/")
+	data, err := ParseHTML(strings.NewReader(html), "text/html", u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := data.WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `digraph microdata {
+	node [shape=box];
+	item0 [label="http://schema.org/Product\n<urn:sku:1>"];
+	value0 [label="The \"Anvil\"", shape=plaintext];
+	item1 [label="http://schema.org/Organization", color=red, penwidth=2];
+	value1 [label="ACME", shape=plaintext];
+	item2 [label="http://schema.org/Product"];
+	value2 [label="Hammer", shape=plaintext];
+	item0 -> value0 [label="name"];
+	item1 -> value1 [label="name"];
+	item0 -> item1 [label="seller"];
+	item2 -> value2 [label="name"];
+	item2 -> item1 [label="seller"];
+}
+`
+	if result := buf.String(); result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	var testTable = []struct {
+		s        string
+		n        int
+		expected string
+	}{
+		{"Anvil", 5, "Anvil"},
+		{"Anvils", 5, "Anvi…"},
+		{"山田太郎です", 4, "山田太…"},
+	}
+
+	for _, test := range testTable {
+		if result := truncate(test.s, test.n); result != test.expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// EncodingSource describes how the encoding of a document was determined.
+type EncodingSource string
+
+// The encoding sources, in order of precedence.
+const (
+	// EncodingBOM is the encoding of the byte order mark of the document.
+	EncodingBOM EncodingSource = "bom"
+
+	// EncodingOverride is the encoding given by the WithEncoding option.
+	EncodingOverride EncodingSource = "override"
+
+	// EncodingHeader is the charset parameter of the content type.
+	EncodingHeader EncodingSource = "header"
+
+	// EncodingMeta is the encoding declared by a meta element in the first
+	// 1024 bytes of the document.
+	EncodingMeta EncodingSource = "meta"
+
+	// EncodingDetected is UTF-8, detected because the first 1024 bytes of
+	// the document are valid UTF-8.
+	EncodingDetected EncodingSource = "detected"
+
+	// EncodingDefault is the windows-1252 fallback encoding.
+	EncodingDefault EncodingSource = "default"
+)
+
+// Encoding describes the character encoding of a parsed document: its name,
+// e.g. "utf-8" or "shift_jis", and how it was determined.
+type Encoding struct {
+	Name   string         `json:"name"`
+	Source EncodingSource `json:"source"`
+}
+
+// String returns the name and the source of the encoding.
+func (e Encoding) String() string {
+	return fmt.Sprintf("%s (%s)", e.Name, e.Source)
+}
+
+// sniffLen is the number of bytes of a document used to determine its
+// encoding.
+const sniffLen = 1024
+
+// boms are the byte order marks and their encodings.
+var boms = []struct {
+	bom  []byte
+	name string
+}{
+	{[]byte{0xef, 0xbb, 0xbf}, "utf-8"},
+	{[]byte{0xfe, 0xff}, "utf-16be"},
+	{[]byte{0xff, 0xfe}, "utf-16le"},
+}
+
+// determineEncoding returns the encoding of the document starting with the
+// given bytes, at most sniffLen bytes or the whole document, following the
+// encoding sniffing algorithm of the HTML specification: the byte order mark,
+// the override, the charset of the content type, the meta elements and
+// finally UTF-8 detection or windows-1252. It also returns the length of the
+// byte order mark.
+func determineEncoding(prefix []byte, contentType, override string) (encoding.Encoding, Encoding, int, error) {
+	for _, b := range boms {
+		if bytes.HasPrefix(prefix, b.bom) {
+			e, name := charset.Lookup(b.name)
+			return e, Encoding{name, EncodingBOM}, len(b.bom), nil
+		}
+	}
+
+	if override != "" {
+		e, name := charset.Lookup(override)
+		if e == nil {
+			return nil, Encoding{}, 0, fmt.Errorf("microdata: unknown encoding %q", override)
+		}
+		return e, Encoding{name, EncodingOverride}, 0, nil
+	}
+
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if e, name := charset.Lookup(params["charset"]); e != nil {
+			return e, Encoding{name, EncodingHeader}, 0, nil
+		}
+	}
+
+	if label := prescan(prefix); label != "" {
+		if e, name := charset.Lookup(label); e != nil {
+			// A document declaring UTF-16 in ASCII is not UTF-16.
+			if strings.HasPrefix(name, "utf-16") {
+				e, name = charset.Lookup("utf-8")
+			}
+			return e, Encoding{name, EncodingMeta}, 0, nil
+		}
+	}
+
+	if len(prefix) == sniffLen {
+		// The prefix may end in the middle of a character.
+		prefix = trimIncompleteRune(prefix)
+	}
+	if utf8.Valid(prefix) {
+		e, name := charset.Lookup("utf-8")
+		return e, Encoding{name, EncodingDetected}, 0, nil
+	}
+	return charmap.Windows1252, Encoding{"windows-1252", EncodingDefault}, 0, nil
+}
+
+// trimIncompleteRune removes a UTF-8 sequence cut off at the end of b.
+func trimIncompleteRune(b []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// prescan returns the encoding label declared by the first meta element with
+// a charset attribute, or with an http-equiv attribute of "content-type" and
+// a content attribute with a charset, in the given bytes.
+func prescan(b []byte) string {
+	z := html.NewTokenizer(bytes.NewReader(b))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			if string(name) != "meta" || !hasAttr {
+				continue
+			}
+
+			var label, content string
+			var isContentType bool
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				switch string(k) {
+				case "charset":
+					label = string(v)
+				case "http-equiv":
+					isContentType = strings.EqualFold(string(v), "content-type")
+				case "content":
+					content = string(v)
+				}
+			}
+			if label == "" && isContentType {
+				label = charsetFromContent(content)
+			}
+			if label = strings.TrimSpace(label); label != "" {
+				return label
+			}
+		}
+	}
+}
+
+// charsetFromContent returns the charset of the content attribute of a meta
+// element, e.g. "text/html; charset=shift_jis".
+func charsetFromContent(content string) string {
+	i := strings.Index(strings.ToLower(content), "charset")
+	if i < 0 {
+		return ""
+	}
+	s := strings.TrimLeft(content[i+len("charset"):], " \t\n\f\r")
+	if !strings.HasPrefix(s, "=") {
+		return ""
+	}
+	s = strings.TrimLeft(s[1:], " \t\n\f\r")
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		if j := strings.IndexByte(s[1:], s[0]); j >= 0 {
+			return s[1 : j+1]
+		}
+		return ""
+	}
+	if j := strings.IndexAny(s, " \t\n\f\r;"); j >= 0 {
+		s = s[:j]
+	}
+	return s
+}
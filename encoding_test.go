@@ -0,0 +1,96 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseHTMLEncoding(t *testing.T) {
+	var testTable = []struct {
+		file        string
+		contentType string
+		opts        []Option
+		name        string
+		encoding    Encoding
+	}{
+		{"shift_jis.html", "", nil, "山田太郎", Encoding{"shift_jis", EncodingMeta}},
+		{"shift_jis.html", "text/html", nil, "山田太郎", Encoding{"shift_jis", EncodingMeta}},
+		{"windows-1252.html", "", nil, "Renée François – café", Encoding{"windows-1252", EncodingDefault}},
+		{"windows-1252.html", "text/html; charset=iso-8859-1", nil, "Renée François – café", Encoding{"windows-1252", EncodingHeader}},
+		{"utf-16le.html", "", nil, "Zoë Åström", Encoding{"utf-16le", EncodingBOM}},
+		{"utf-16be.html", "text/html; charset=windows-1252", nil, "Zoë Åström", Encoding{"utf-16be", EncodingBOM}},
+		{"shift_jis.html", "text/html; charset=utf-8", []Option{WithEncoding("sjis")}, "山田太郎", Encoding{"shift_jis", EncodingOverride}},
+		{"utf-16le.html", "", []Option{WithEncoding("windows-1252")}, "Zoë Åström", Encoding{"utf-16le", EncodingBOM}},
+	}
+
+	u, _ := url.Parse("http://example.com/")
+	for _, test := range testTable {
+		f, err := os.Open(filepath.Join("testdata", test.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ParseHTML(f, test.contentType, u, test.opts...)
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+
+		if *data.Encoding != test.encoding {
+			t.Errorf("%s: Result should have been \"%v\", but it was \"%v\"", test.file, test.encoding, *data.Encoding)
+		}
+		if len(data.Items) != 1 {
+			t.Errorf("%s: Result should have been 1 item, but it was %d", test.file, len(data.Items))
+			continue
+		}
+		if result := data.Items[0].Properties["name"][0]; result != test.name {
+			t.Errorf("%s: Result should have been \"%s\", but it was \"%s\"", test.file, test.name, result)
+		}
+	}
+}
+
+func TestParseHTMLUnknownEncoding(t *testing.T) {
+	u, _ := url.Parse("http://example.com/")
+	_, err := ParseHTML(strings.NewReader("<p>"), "", u, WithEncoding("no-such-encoding"))
+	if err == nil {
+		t.Error("Result should have been an error, but it was nil")
+	}
+}
+
+func TestDetermineEncoding(t *testing.T) {
+	var testTable = []struct {
+		prefix      string
+		contentType string
+		expected    Encoding
+	}{
+		{"\xef\xbb\xbf<p>", "text/html; charset=shift_jis", Encoding{"utf-8", EncodingBOM}},
+		{"<p>", "text/html; charset=Shift_JIS", Encoding{"shift_jis", EncodingHeader}},
+		{"<p>", "text/html; charset=no-such-encoding", Encoding{"utf-8", EncodingDetected}},
+		{`<meta charset="euc-jp">`, "text/html", Encoding{"euc-jp", EncodingMeta}},
+		{`<meta http-equiv="Content-Type" content="text/html; charset='koi8-r'">`, "", Encoding{"koi8-r", EncodingMeta}},
+		{`<meta http-equiv="refresh" content="charset=koi8-r">`, "", Encoding{"utf-8", EncodingDetected}},
+		{`<meta charset="utf-16le">`, "", Encoding{"utf-8", EncodingMeta}},
+		{"<p>caf\xc3\xa9</p>", "", Encoding{"utf-8", EncodingDetected}},
+		{"<p>caf\xc3", "", Encoding{"windows-1252", EncodingDefault}},
+		{strings.Repeat("a", sniffLen-1) + "\xc3", "", Encoding{"utf-8", EncodingDetected}},
+		{"<p>caf\xe9</p>", "", Encoding{"windows-1252", EncodingDefault}},
+		{"", "", Encoding{"utf-8", EncodingDetected}},
+	}
+
+	for _, test := range testTable {
+		_, result, _, err := determineEncoding([]byte(test.prefix), test.contentType, "")
+		if err != nil {
+			t.Errorf("%q: %v", test.prefix, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("%q: Result should have been \"%v\", but it was \"%v\"", test.prefix, test.expected, result)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Filter returns the microdata with only the top-level items for which keep
+// returns true. The items are shared with m.
+func (m *Microdata) Filter(keep func(*Item) bool) *Microdata {
+	filtered := *m
+	filtered.Items = nil
+	for _, item := range m.Items {
+		if keep(item) {
+			filtered.Items = append(filtered.Items, item)
+		}
+	}
+	return &filtered
+}
+
+// TypeFilter selects items by their types. A type matches an item type when
+// they're equal, when both are variants of the same schema.org type, e.g.
+// https://schema.org/Product and http://schema.org/Product, or when the type
+// is a name without a vocabulary, e.g. "Product", and the item type ends with
+// it after a "/" or "#".
+type TypeFilter struct {
+	// Types are the types of the items to keep. All items are kept when it's
+	// empty.
+	Types []string
+
+	// Exclude are the types of the items to remove, from the top-level items
+	// and from the properties of the other items.
+	Exclude []string
+
+	// Nested promotes the nested items matching Types to top-level items,
+	// unless they're nested in a matching item.
+	Nested bool
+}
+
+// WithTypeFilter applies the type filter to the parsed microdata. Top-level
+// items which don't match aren't parsed, unless nested items are promoted.
+func WithTypeFilter(f *TypeFilter) Option {
+	return func(p *parser) {
+		p.filter = f
+	}
+}
+
+// Match reports whether the item has one of the types of the filter, or the
+// filter has no types, and none of the excluded types.
+func (f *TypeFilter) Match(item *Item) bool {
+	if f.excluded(item) {
+		return false
+	}
+	return len(f.Types) == 0 || matchTypes(f.Types, item.Types)
+}
+
+// excluded reports whether the item has one of the excluded types.
+func (f *TypeFilter) excluded(item *Item) bool {
+	return matchTypes(f.Exclude, item.Types)
+}
+
+// Apply returns the microdata with the items matching the filter. The
+// excluded items are removed from the properties of the items in place.
+func (f *TypeFilter) Apply(m *Microdata) *Microdata {
+	for _, item := range m.Items {
+		f.prune(item)
+	}
+	if !f.Nested {
+		return m.Filter(f.Match)
+	}
+
+	filtered := *m
+	filtered.Items = nil
+	seen := make(map[*Item]bool)
+	var find func(item *Item)
+	find = func(item *Item) {
+		// Items held by more than one item, through itemref, are promoted
+		// once.
+		if seen[item] || f.excluded(item) {
+			return
+		}
+		seen[item] = true
+		if f.Match(item) {
+			filtered.Items = append(filtered.Items, item)
+			return
+		}
+		for _, name := range item.PropertyNames() {
+			for _, v := range item.Properties[name] {
+				if sub, ok := v.(*Item); ok {
+					find(sub)
+				}
+			}
+		}
+	}
+	for _, item := range m.Items {
+		find(item)
+	}
+	return &filtered
+}
+
+// prune removes the excluded items from the properties of the item and its
+// nested items. Properties left without values are removed.
+func (f *TypeFilter) prune(item *Item) {
+	if len(f.Exclude) == 0 {
+		return
+	}
+	for _, name := range item.PropertyNames() {
+		values := item.Properties[name]
+		details := item.details[name]
+		if len(details) != len(values) {
+			details = nil
+		}
+
+		var keptValues ValueList
+		var keptDetails []valueDetails
+		for n, v := range values {
+			if sub, ok := v.(*Item); ok {
+				if f.excluded(sub) {
+					continue
+				}
+				f.prune(sub)
+			}
+			keptValues = append(keptValues, v)
+			if details != nil {
+				keptDetails = append(keptDetails, details[n])
+			}
+		}
+		if len(keptValues) == len(values) {
+			continue
+		}
+
+		if len(keptValues) == 0 {
+			item.removeProperty(name)
+			continue
+		}
+		item.Properties[name] = keptValues
+		if details != nil {
+			item.details[name] = keptDetails
+		}
+	}
+}
+
+// removeProperty removes the property from the item.
+func (i *Item) removeProperty(name string) {
+	delete(i.Properties, name)
+	delete(i.details, name)
+	for n, p := range i.order {
+		if p == name {
+			i.order = append(i.order[:n], i.order[n+1:]...)
+			break
+		}
+	}
+}
+
+// matchTypes reports whether one of the item types matches one of the types,
+// see TypeFilter.
+func matchTypes(types, itemTypes []string) bool {
+	for _, t := range types {
+		for _, it := range itemTypes {
+			if matchType(t, it) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchType reports whether the item type matches the type, see TypeFilter.
+func matchType(t, itemType string) bool {
+	if t == itemType {
+		return true
+	}
+	if !strings.ContainsAny(t, "/#") {
+		i := strings.LastIndexAny(itemType, "/#")
+		return i >= 0 && itemType[i+1:] == t
+	}
+	a, _ := normalizeType(t)
+	b, _ := normalizeType(itemType)
+	return a == b && strings.HasPrefix(a, schemaOrgURI)
+}
+
+// skipItem reports whether the top-level item of the node can be skipped
+// without reading it, because its types don't match the filter. The types
+// aren't known before normalizing the vocabulary, and the nested items may
+// match when they're promoted.
+func (p *parser) skipItem(node *html.Node) bool {
+	if p.filter == nil || p.filter.Nested || p.normalizeVocabulary {
+		return false
+	}
+	item := &Item{Types: strings.Fields(attrValue("itemtype", node))}
+	return !p.filter.Match(item)
+}
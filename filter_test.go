@@ -0,0 +1,110 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var filterSnippet = `<html><body>
+<ol itemscope itemtype="https://schema.org/BreadcrumbList">
+	<li itemprop="itemListElement" itemscope itemtype="https://schema.org/ListItem"><span itemprop="name">Tools</span></li>
+</ol>
+<div itemscope itemtype="http://schema.org/Product">
+	<span itemprop="name">Anvil</span>
+	<div itemprop="offers" itemscope itemtype="http://schema.org/Offer"><span itemprop="price">9.99</span></div>
+	<div itemprop="aggregateRating" itemscope itemtype="http://schema.org/AggregateRating"><span itemprop="ratingValue">4</span></div>
+</div>
+<div itemscope itemtype="http://schema.org/WebPage">
+	<div itemprop="mainEntity" itemscope itemtype="http://schema.org/Product"><span itemprop="name">Hammer</span></div>
+</div>
+</body></html>`
+
+func parseFilterSnippet(t *testing.T, f *TypeFilter) *Microdata {
+	u, _ := url.Parse("http://example.com/")
+	data, err := ParseHTML(strings.NewReader(filterSnippet), "text/html", u, WithTypeFilter(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func itemNames(data *Microdata) []string {
+	var names []string
+	for _, item := range data.Items {
+		name, _ := item.Properties["name"][0].(string)
+		names = append(names, item.Types[0]+" "+name)
+	}
+	return names
+}
+
+func TestWithTypeFilter(t *testing.T) {
+	testTable := []struct {
+		filter   *TypeFilter
+		expected []string
+	}{
+		{&TypeFilter{Types: []string{"Product"}}, []string{"http://schema.org/Product Anvil"}},
+		{&TypeFilter{Types: []string{"https://schema.org/Product"}}, []string{"http://schema.org/Product Anvil"}},
+		{&TypeFilter{Types: []string{"Product"}, Nested: true}, []string{"http://schema.org/Product Anvil", "http://schema.org/Product Hammer"}},
+		{&TypeFilter{Exclude: []string{"BreadcrumbList", "WebPage"}}, []string{"http://schema.org/Product Anvil"}},
+		{&TypeFilter{Types: []string{"http://example.com/Product"}}, nil},
+	}
+
+	for _, test := range testTable {
+		data := parseFilterSnippet(t, test.filter)
+		if actual := itemNames(data); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Result should have been \"%v\", but it was \"%v\"", test.expected, actual)
+		}
+	}
+}
+
+func TestWithTypeFilterPrune(t *testing.T) {
+	data := parseFilterSnippet(t, &TypeFilter{Types: []string{"Product"}, Exclude: []string{"AggregateRating"}})
+	if len(data.Items) != 1 {
+		t.Fatalf("Result should have been 1 item, but it was %d", len(data.Items))
+	}
+
+	item := data.Items[0]
+	if _, ok := item.Properties["aggregateRating"]; ok {
+		t.Errorf("Result should have been no aggregateRating, but it was %v", item.Properties["aggregateRating"])
+	}
+	expected := []string{"name", "offers"}
+	if actual := item.PropertyNames(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", expected, actual)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	data := parseFilterSnippet(t, &TypeFilter{})
+	filtered := data.Filter(func(item *Item) bool {
+		return len(item.Properties["name"]) > 0
+	})
+
+	expected := []string{"http://schema.org/Product Anvil"}
+	if actual := itemNames(filtered); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", expected, actual)
+	}
+	if len(data.Items) != 3 {
+		t.Errorf("Result should have been 3 items, but it was %d", len(data.Items))
+	}
+}
+
+func TestWithTypeFilterNestedShared(t *testing.T) {
+	html := `<div itemscope itemtype="http://schema.org/WebPage" itemref="product"></div>
+<div itemscope itemtype="http://schema.org/WebPage" itemref="product"></div>
+<div id="product" itemprop="mainEntity" itemscope itemtype="http://schema.org/Product"><span itemprop="name">Anvil</span></div>`
+
+	u, _ := url.Parse("http://example.com/")
+	data, err := ParseHTML(strings.NewReader(html), "text/html", u, WithTypeFilter(&TypeFilter{Types: []string{"Product"}, Nested: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"http://schema.org/Product Anvil"}
+	if actual := itemNames(data); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", expected, actual)
+	}
+}
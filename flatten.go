@@ -0,0 +1,203 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValueKind is the kind of the value of a row.
+type ValueKind string
+
+// The value kinds.
+const (
+	// NoValue is the kind of the row of an item without properties.
+	NoValue ValueKind = ""
+
+	// TextValue is the text of an element or of a content attribute.
+	TextValue ValueKind = "text"
+
+	// URLValue is the absolute URL of a src or href attribute.
+	URLValue ValueKind = "url"
+
+	// DataValue is a machine-readable value, like the datetime attribute of
+	// a time element or the value attribute of a data element.
+	DataValue ValueKind = "data"
+
+	// ItemValue is a nested item. The value of the row is the path of the
+	// item.
+	ItemValue ValueKind = "item"
+)
+
+// Row is a property value of an item in the flattened representation of
+// microdata, see Microdata.Flatten.
+type Row struct {
+	// Path locates the item, e.g. "items[0]" or "items[0].offers[0]".
+	Path string `json:"path"`
+
+	// Type holds the types of the item, separated by a space.
+	Type string `json:"type"`
+
+	// ID is the global identifier of the item.
+	ID string `json:"id"`
+
+	Property string    `json:"property"`
+	Value    string    `json:"value"`
+	Kind     ValueKind `json:"kind"`
+}
+
+// Flatten returns the items and their nested items as rows, one per property
+// value, in document order. The rows of a nested item follow the row holding
+// it. An item without properties has a single row without property and value.
+// An item held by more than one property, e.g. through itemref, has the rows
+// of its first path only; the other rows holding it have that path as value.
+func (m *Microdata) Flatten() []Row {
+	var rows []Row
+	paths := make(map[*Item]string)
+
+	var flatten func(path string, item *Item)
+	flatten = func(path string, item *Item) {
+		paths[item] = path
+		typ := strings.Join(item.Types, " ")
+		names := item.PropertyNames()
+		if len(names) == 0 {
+			rows = append(rows, Row{Path: path, Type: typ, ID: item.ID})
+			return
+		}
+
+		for _, name := range names {
+			for i, v := range item.Properties[name] {
+				row := Row{Path: path, Type: typ, ID: item.ID, Property: name, Kind: TextValue}
+				sub, ok := v.(*Item)
+				if !ok {
+					switch item.valueKind(name, i) {
+					case urlValue:
+						row.Kind = URLValue
+					case dataValue:
+						row.Kind = DataValue
+					}
+					row.Value = fmt.Sprint(v)
+					rows = append(rows, row)
+					continue
+				}
+
+				row.Kind = ItemValue
+				if subPath, seen := paths[sub]; seen {
+					row.Value = subPath
+					rows = append(rows, row)
+					continue
+				}
+				row.Value = fmt.Sprintf("%s.%s[%d]", path, name, i)
+				rows = append(rows, row)
+				flatten(row.Value, sub)
+			}
+		}
+	}
+
+	for i, item := range m.Items {
+		if _, seen := paths[item]; !seen {
+			flatten(fmt.Sprintf("items[%d]", i), item)
+		}
+	}
+	return rows
+}
+
+// Unflatten rebuilds the items from rows, the reverse of Microdata.Flatten.
+// Items are identified by their path; the top-level items are the items whose
+// path isn't the value of a row of another item, in order of appearance. It
+// returns an error when a row has an unknown kind, a nested item has no rows,
+// or an item holds itself.
+func Unflatten(rows []Row) (*Microdata, error) {
+	items := make(map[string]*Item)
+	defined := make(map[string]bool)
+	nested := make(map[string]bool)
+	children := make(map[string][]string)
+	var order []string
+
+	get := func(path string) *Item {
+		item, ok := items[path]
+		if !ok {
+			item = NewItem()
+			items[path] = item
+			order = append(order, path)
+		}
+		return item
+	}
+
+	for n, row := range rows {
+		item := get(row.Path)
+		if !defined[row.Path] {
+			defined[row.Path] = true
+			item.Types = append(item.Types, strings.Fields(row.Type)...)
+			item.ID = row.ID
+		}
+
+		switch row.Kind {
+		case NoValue:
+		case TextValue:
+			item.addString(row.Property, row.Value, valueDetails{})
+		case URLValue:
+			item.addString(row.Property, row.Value, valueDetails{kind: urlValue})
+		case DataValue:
+			item.addString(row.Property, row.Value, valueDetails{kind: dataValue})
+		case ItemValue:
+			nested[row.Value] = true
+			children[row.Path] = append(children[row.Path], row.Value)
+			item.addItem(row.Property, get(row.Value))
+		default:
+			return nil, fmt.Errorf("microdata: row %d: unknown value kind %q", n+1, row.Kind)
+		}
+	}
+
+	if path, ok := cycle(order, children); ok {
+		return nil, fmt.Errorf("microdata: item %q holds itself", path)
+	}
+
+	data := &Microdata{}
+	for _, path := range order {
+		if !defined[path] {
+			return nil, fmt.Errorf("microdata: item %q has no rows", path)
+		}
+		if !nested[path] {
+			data.addItem(items[path])
+		}
+	}
+	return data, nil
+}
+
+// cycle returns the path of an item which holds itself through its nested
+// items, if any.
+func cycle(paths []string, children map[string][]string) (string, bool) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var visit func(path string) (string, bool)
+	visit = func(path string) (string, bool) {
+		state[path] = visiting
+		for _, child := range children[path] {
+			switch state[child] {
+			case visiting:
+				return child, true
+			case unvisited:
+				if p, ok := visit(child); ok {
+					return p, true
+				}
+			}
+		}
+		state[path] = visited
+		return "", false
+	}
+	for _, path := range paths {
+		if state[path] == unvisited {
+			if p, ok := visit(path); ok {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,107 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	html := `<div itemscope itemtype="http://schema.org/Product" itemid="urn:sku:1">
+	<span itemprop="name">Anvil</span>
+	<a itemprop="url" href="/anvil">Anvil</a>
+	<time itemprop="releaseDate" datetime="2015-01-01">New Year's Day</time>
+	<div itemprop="offers" itemscope itemtype="http://schema.org/Offer"><span itemprop="price">9.99</span></div>
+</div>
+<div itemscope></div>`
+
+	u, _ := url.Parse("http://example.com/")
+	data, err := ParseHTML(strings.NewReader(html), "text/html", u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Row{
+		{"items[0]", "http://schema.org/Product", "urn:sku:1", "name", "Anvil", TextValue},
+		{"items[0]", "http://schema.org/Product", "urn:sku:1", "url", "http://example.com/anvil", URLValue},
+		{"items[0]", "http://schema.org/Product", "urn:sku:1", "releaseDate", "2015-01-01", DataValue},
+		{"items[0]", "http://schema.org/Product", "urn:sku:1", "offers", "items[0].offers[0]", ItemValue},
+		{"items[0].offers[0]", "http://schema.org/Offer", "", "price", "9.99", TextValue},
+		{"items[1]", "", "", "", "", NoValue},
+	}
+	if actual := data.Flatten(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", expected, actual)
+	}
+
+	result, err := Unflatten(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := result.Flatten(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", expected, actual)
+	}
+}
+
+func TestFlattenSharedItem(t *testing.T) {
+	html := `<div itemscope itemtype="http://schema.org/Product" itemref="seller"><span itemprop="name">Anvil</span></div>
+<div itemscope itemtype="http://schema.org/Product" itemref="seller"><span itemprop="name">Hammer</span></div>
+<div id="seller" itemprop="seller" itemscope itemtype="http://schema.org/Organization"><span itemprop="name">ACME</span></div>`
+
+	u, _ := url.Parse("http://example.com/")
+	data, err := ParseHTML(strings.NewReader(html), "text/html", u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := data.Flatten()
+	expected := []Row{
+		{"items[0]", "http://schema.org/Product", "", "name", "Anvil", TextValue},
+		{"items[0]", "http://schema.org/Product", "", "seller", "items[0].seller[0]", ItemValue},
+		{"items[0].seller[0]", "http://schema.org/Organization", "", "name", "ACME", TextValue},
+		{"items[1]", "http://schema.org/Product", "", "name", "Hammer", TextValue},
+		{"items[1]", "http://schema.org/Product", "", "seller", "items[0].seller[0]", ItemValue},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("Result should have been \"%v\", but it was \"%v\"", expected, rows)
+	}
+
+	result, err := Unflatten(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Items[0].Properties["seller"][0] != result.Items[1].Properties["seller"][0] {
+		t.Errorf("Result should have been a shared item, but it was \"%v\"", result.Items)
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	testRoundTrip(t, func(data *Microdata) (*Microdata, error) {
+		return Unflatten(data.Flatten())
+	})
+}
+
+func TestUnflattenError(t *testing.T) {
+	var testTable = []struct {
+		rows     []Row
+		expected string
+	}{
+		{[]Row{{Path: "items[0]", Property: "name", Value: "Anvil", Kind: "number"}}, `microdata: row 1: unknown value kind "number"`},
+		{[]Row{{Path: "items[0]", Property: "offers", Value: "items[0].offers[0]", Kind: ItemValue}}, `microdata: item "items[0].offers[0]" has no rows`},
+		{[]Row{{Path: "items[0]", Property: "knows", Value: "items[0]", Kind: ItemValue}}, `microdata: item "items[0]" holds itself`},
+		{[]Row{
+			{Path: "items[0]", Property: "knows", Value: "items[0].knows[0]", Kind: ItemValue},
+			{Path: "items[0].knows[0]", Property: "knows", Value: "items[0]", Kind: ItemValue},
+		}, `microdata: item "items[0]" holds itself`},
+	}
+
+	for _, test := range testTable {
+		_, err := Unflatten(test.rows)
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%v\"", test.expected, err)
+		}
+	}
+}
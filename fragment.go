@@ -0,0 +1,152 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLMode selects how the HTML of the elements of text values is captured,
+// see Value.HTML.
+type HTMLMode int
+
+const (
+	// NoHTML captures no HTML, the default.
+	NoHTML HTMLMode = iota
+
+	// RawHTML captures the inner HTML of the elements as is.
+	RawHTML
+
+	// SanitizedHTML captures the inner HTML of the elements with only the
+	// elements and attributes of rich text, like paragraphs, lists, emphasis
+	// and links. Scripts, styles, embedded content and forms are removed with
+	// their content, other elements are replaced by their content. URLs are
+	// resolved and only http, https and mailto URLs are kept.
+	SanitizedHTML
+)
+
+// ParseHTMLMode returns the HTML mode of the given name: none, raw or
+// sanitized.
+func ParseHTMLMode(s string) (HTMLMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none":
+		return NoHTML, nil
+	case "raw":
+		return RawHTML, nil
+	case "sanitized":
+		return SanitizedHTML, nil
+	}
+	return NoHTML, fmt.Errorf("microdata: unknown HTML mode %q", s)
+}
+
+// WithHTML captures the HTML of the elements of text values, e.g. the
+// paragraphs and links of a description, in the given mode.
+func WithHTML(mode HTMLMode) Option {
+	return func(p *parser) {
+		p.htmlMode = mode
+	}
+}
+
+// innerHTML returns the inner HTML of the node in the HTML mode of the parser.
+func (p *parser) innerHTML(node *html.Node) string {
+	var buf bytes.Buffer
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if p.htmlMode == SanitizedHTML {
+			for _, n := range p.sanitize(c) {
+				html.Render(&buf, n)
+			}
+			continue
+		}
+		html.Render(&buf, c)
+	}
+	return buf.String()
+}
+
+// removedElements are removed with their content by the sanitizer.
+var removedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Template: true, atom.Noscript: true,
+	atom.Iframe: true, atom.Object: true, atom.Embed: true, atom.Applet: true,
+	atom.Audio: true, atom.Video: true, atom.Canvas: true, atom.Svg: true,
+	atom.Math: true, atom.Form: true, atom.Input: true, atom.Button: true,
+	atom.Select: true, atom.Textarea: true, atom.Head: true, atom.Title: true,
+	atom.Link: true, atom.Meta: true, atom.Base: true,
+}
+
+// allowedElements are the elements kept by the sanitizer with their allowed
+// attributes, besides lang and dir.
+var allowedElements = map[atom.Atom][]string{
+	atom.A: {"href", "title"}, atom.Abbr: {"title"}, atom.B: nil,
+	atom.Blockquote: {"cite"}, atom.Br: nil, atom.Caption: nil, atom.Cite: nil,
+	atom.Code: nil, atom.Dd: nil, atom.Del: {"cite"}, atom.Dfn: nil, atom.Div: nil,
+	atom.Dl: nil, atom.Dt: nil, atom.Em: nil, atom.Figcaption: nil, atom.Figure: nil,
+	atom.H1: nil, atom.H2: nil, atom.H3: nil, atom.H4: nil, atom.H5: nil, atom.H6: nil,
+	atom.Hr: nil, atom.I: nil, atom.Img: {"src", "alt", "title", "width", "height"},
+	atom.Ins: {"cite"}, atom.Kbd: nil, atom.Li: nil, atom.Mark: nil,
+	atom.Ol: {"start"}, atom.P: nil, atom.Pre: nil, atom.Q: {"cite"}, atom.S: nil,
+	atom.Samp: nil, atom.Small: nil, atom.Span: nil, atom.Strong: nil, atom.Sub: nil,
+	atom.Sup: nil, atom.Table: nil, atom.Tbody: nil, atom.Td: {"colspan", "rowspan"},
+	atom.Tfoot: nil, atom.Th: {"colspan", "rowspan", "scope"}, atom.Thead: nil,
+	atom.Time: {"datetime"}, atom.Tr: nil, atom.U: nil, atom.Ul: nil, atom.Var: nil,
+}
+
+// urlAttributes are the attributes holding URLs.
+var urlAttributes = map[string]bool{"href": true, "src": true, "cite": true}
+
+// sanitize returns a sanitized copy of the node, see SanitizedHTML.
+func (p *parser) sanitize(n *html.Node) []*html.Node {
+	switch n.Type {
+	case html.TextNode:
+		return []*html.Node{{Type: html.TextNode, Data: n.Data}}
+	case html.ElementNode:
+	default:
+		return nil
+	}
+	if removedElements[n.DataAtom] {
+		return nil
+	}
+
+	var children []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		children = append(children, p.sanitize(c)...)
+	}
+
+	allowed, ok := allowedElements[n.DataAtom]
+	if !ok || n.Namespace != "" {
+		return children
+	}
+
+	clone := &html.Node{Type: html.ElementNode, DataAtom: n.DataAtom, Data: n.Data}
+	for _, attr := range n.Attr {
+		if attr.Namespace != "" || !(attr.Key == "lang" || attr.Key == "dir" || contains(allowed, attr.Key)) {
+			continue
+		}
+		if urlAttributes[attr.Key] {
+			u, err := p.baseURL.Parse(strings.TrimSpace(attr.Val))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https" && (u.Scheme != "mailto" || attr.Key != "href")) {
+				continue
+			}
+			attr.Val = u.String()
+		}
+		clone.Attr = append(clone.Attr, html.Attribute{Key: attr.Key, Val: attr.Val})
+	}
+	for _, c := range children {
+		clone.AppendChild(c)
+	}
+	return []*html.Node{clone}
+}
+
+// contains reports whether the list contains the string.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var fragmentSnippet = `<div itemscope itemtype="http://schema.org/Article">
+	<div itemprop="articleBody"><p class="lead" onclick="track()">Read <a href="/more" target="_blank">more</a> or <a href="javascript:alert(1)">this</a>.</p><script>track()</script><custom-note>Note <em>well</em></custom-note><img src="/a.png" alt="A" onerror="x()"><iframe src="/ad"></iframe></div>
+	<meta itemprop="headline" content="Headline">
+	<a itemprop="url" href="/article">Article</a>
+</div>`
+
+func TestWithHTML(t *testing.T) {
+	u, _ := url.Parse("http://example.com/")
+
+	var testTable = []struct {
+		mode     HTMLMode
+		property string
+		expected string
+	}{
+		{NoHTML, "articleBody", ""},
+		{RawHTML, "articleBody", `<p class="lead" onclick="track()">Read <a href="/more" target="_blank">more</a> or <a href="javascript:alert(1)">this</a>.</p><script>track()</script><custom-note>Note <em>well</em></custom-note><img src="/a.png" alt="A" onerror="x()"/><iframe src="/ad"></iframe>`},
+		{SanitizedHTML, "articleBody", `<p>Read <a href="http://example.com/more">more</a> or <a>this</a>.</p>Note <em>well</em><img src="http://example.com/a.png" alt="A"/>`},
+		{SanitizedHTML, "headline", ""},
+		{SanitizedHTML, "url", ""},
+	}
+
+	for _, test := range testTable {
+		data, err := ParseHTML(strings.NewReader(fragmentSnippet), "text/html", u, WithHTML(test.mode))
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := data.Items[0].Values(test.property)
+		if result := values[0].HTML; result != test.expected {
+			t.Errorf("%s: Result should have been \"%s\", but it was \"%s\"", test.property, test.expected, result)
+		}
+	}
+}
+
+func TestParseHTMLMode(t *testing.T) {
+	var testTable = []struct {
+		s        string
+		expected HTMLMode
+	}{
+		{"", NoHTML},
+		{"none", NoHTML},
+		{"raw", RawHTML},
+		{"Sanitized", SanitizedHTML},
+	}
+
+	for _, test := range testTable {
+		result, err := ParseHTMLMode(test.s)
+		if err != nil || result != test.expected {
+			t.Errorf("%q: Result should have been %d, but it was %d (%v)", test.s, test.expected, result, err)
+		}
+	}
+	if _, err := ParseHTMLMode("clean"); err == nil {
+		t.Error("Result should have been an error, but it was nil")
+	}
+}
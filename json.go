@@ -0,0 +1,62 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// UnmarshalJSON decodes the JSON representation of an item. The types and
+// properties are always allocated, mirroring NewItem.
+func (i *Item) UnmarshalJSON(b []byte) error {
+	type item Item
+	v := item(*NewItem())
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.Types == nil {
+		v.Types = make([]string, 0)
+	}
+	if v.Properties == nil {
+		v.Properties = make(PropertyMap, 0)
+	}
+	*i = Item(v)
+	return nil
+}
+
+// UnmarshalJSON decodes a list of property values. JSON objects are decoded
+// as nested items and JSON strings as text values, so the result is identical
+// to the list produced by the parser.
+func (l *ValueList) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*l = nil
+		return nil
+	}
+
+	values := make(ValueList, 0, len(raw))
+	for _, r := range raw {
+		r = bytes.TrimSpace(r)
+		if len(r) > 0 && r[0] == '{' {
+			item := NewItem()
+			if err := json.Unmarshal(r, item); err != nil {
+				return err
+			}
+			values = append(values, item)
+			continue
+		}
+
+		var s string
+		if err := json.Unmarshal(r, &s); err != nil {
+			return err
+		}
+		values = append(values, s)
+	}
+	*l = values
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(data *Microdata) (*Microdata, error) {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		var result Microdata
+		err = json.Unmarshal(b, &result)
+		return &result, err
+	})
+}
+
+// testRoundTrip checks that the given round trip of the microdata of the test
+// snippets is the identity.
+func testRoundTrip(t *testing.T, roundTrip func(*Microdata) (*Microdata, error)) {
+	var testTable = []struct {
+		name    string
+		snippet string
+		baseURL string
+	}{
+		{"book", bookSnippet, ""},
+		{"gallery", gallerySnippet, ""},
+		{"blog", blogSnippet, "http://blog.example.com/progress-report"},
+		{"types", `<div itemscope itemtype="http://example.com/B http://example.com/A http://example.com/A"><span itemprop="name">B</span></div>`, ""},
+	}
+
+	for _, test := range testTable {
+		u, _ := url.Parse(test.baseURL)
+		data, err := ParseHTML(strings.NewReader(test.snippet), "charset=utf-8", u)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := roundTrip(data)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(plainItems(result.Items), plainItems(data.Items)) {
+			t.Errorf("%s: round trip should have been the identity, but it was \"%+v\"", test.name, plainItems(result.Items))
+		}
+	}
+}
+
+// plainItem is an item without the unexported property order and value
+// details, compared by the round trip tests.
+type plainItem struct {
+	Types      []string
+	ID         string
+	Properties map[string][]interface{}
+}
+
+// plainItems returns the items and their nested items as plain items.
+func plainItems(items []*Item) []plainItem {
+	var plain []plainItem
+	for _, item := range items {
+		p := plainItem{
+			Types:      append([]string{}, item.Types...),
+			ID:         item.ID,
+			Properties: make(map[string][]interface{}),
+		}
+		for name, values := range item.Properties {
+			for _, v := range values {
+				if sub, ok := v.(*Item); ok {
+					v = plainItems([]*Item{sub})[0]
+				}
+				p.Properties[name] = append(p.Properties[name], v)
+			}
+		}
+		plain = append(plain, p)
+	}
+	return plain
+}
+
+func TestUnmarshalNestedItem(t *testing.T) {
+	b := []byte(`{"items":[{"type":["http://schema.org/BlogPosting"],"properties":{"comment":[{"type":["http://schema.org/UserComments"],"properties":{"url":["#c1"]}}]}}]}`)
+
+	var data Microdata
+	if err := json.Unmarshal(b, &data); err != nil {
+		t.Fatal(err)
+	}
+
+	item, ok := data.Items[0].Properties["comment"][0].(*Item)
+	if !ok {
+		t.Fatalf("Result should have been an *Item, but it was \"%T\"", data.Items[0].Properties["comment"][0])
+	}
+	result := item.Properties["url"][0].(string)
+	expected := "#c1"
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestUnmarshalInvalidValue(t *testing.T) {
+	b := []byte(`{"type":[],"properties":{"count":[1]}}`)
+
+	var item Item
+	if err := json.Unmarshal(b, &item); err == nil {
+		t.Error("Result should have been an error, but it was nil")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/language"
+)
+
+// langTagPattern is the LANGTAG production of N-Triples.
+var langTagPattern = regexp.MustCompile(`^[a-zA-Z]+(-[a-zA-Z0-9]+)*$`)
+
+// validTag returns the language tag when it's a well-formed BCP 47 tag, or ""
+// for an unknown language.
+func validTag(tag string) string {
+	tag = strings.TrimSpace(tag)
+	if !langTagPattern.MatchString(tag) {
+		return ""
+	}
+	if _, err := language.Parse(tag); err != nil {
+		return ""
+	}
+	return tag
+}
+
+// lang returns the language of the node: the xml:lang or lang attribute of the
+// node or of its nearest ancestor with one, or the default language of the
+// document. An empty attribute means the language is unknown.
+func (p *parser) lang(node *html.Node) string {
+	for n := node; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		if tag, ok := langAttr(n); ok {
+			return tag
+		}
+	}
+	return p.language
+}
+
+// langAttr returns the language of the xml:lang attribute, or else the lang
+// attribute, of the node. A malformed language tag is an unknown language.
+func langAttr(n *html.Node) (string, bool) {
+	var lang string
+	var found bool
+	for _, attr := range n.Attr {
+		switch {
+		case attr.Key == "xml:lang", attr.Namespace == "xml" && attr.Key == "lang":
+			return validTag(attr.Val), true
+		case attr.Namespace == "" && attr.Key == "lang":
+			lang, found = validTag(attr.Val), true
+		}
+	}
+	return lang, found
+}
+
+// readLanguagePragma sets the default language of the document from the meta
+// element when it's a Content-Language pragma with a single language. It
+// reports whether the element is a Content-Language pragma.
+func (p *parser) readLanguagePragma(n *html.Node) bool {
+	equiv, _ := getAttr("http-equiv", n)
+	content, ok := getAttr("content", n)
+	if !ok || !strings.EqualFold(strings.TrimSpace(equiv), "content-language") {
+		return false
+	}
+	if tag := contentLanguage(content); tag != "" {
+		p.language = tag
+	}
+	return true
+}
+
+// contentLanguage returns the language of a Content-Language header or meta
+// element. A list of languages has no single language and returns "", like a
+// malformed language tag.
+func contentLanguage(s string) string {
+	return validTag(s)
+}
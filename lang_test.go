@@ -0,0 +1,160 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var langSnippet = `<html lang="en">
+<head><meta http-equiv="Content-Language" content="de"></head>
+<body>
+<div itemscope itemtype="http://schema.org/Product">
+	<span itemprop="name">Anvil</span>
+	<span itemprop="name" lang="fr">Enclume</span>
+	<p lang="">
+		<span itemprop="description">Heavy</span>
+	</p>
+	<div xml:lang="nl" lang="de"><span itemprop="description">Zwaar</span></div>
+	<meta itemprop="alternateName" content="Amboss" lang="de">
+	<a itemprop="url" href="/anvil" lang="en">Anvil</a>
+	<data itemprop="sku" value="A-1">A-1</data>
+	<div itemprop="brand" itemscope itemtype="http://schema.org/Brand"><span itemprop="name">ACME</span></div>
+</div>
+</body>
+</html>`
+
+func TestLang(t *testing.T) {
+	data := ParseData(langSnippet, t)
+	item := data.Items[0]
+
+	var testTable = []struct {
+		property string
+		n        int
+		value    interface{}
+		lang     string
+	}{
+		{"name", 0, "Anvil", "en"},
+		{"name", 1, "Enclume", "fr"},
+		{"description", 0, "Heavy", ""},
+		{"description", 1, "Zwaar", "nl"},
+		{"alternateName", 0, "Amboss", "de"},
+		{"url", 0, "http://example.com/anvil", ""},
+		{"sku", 0, "A-1", ""},
+		{"brand", 0, item.Properties["brand"][0], ""},
+	}
+
+	for _, test := range testTable {
+		values := item.Values(test.property)
+		if len(values) <= test.n {
+			t.Errorf("%s: Result should have had %d values, but it was %v", test.property, test.n+1, values)
+			continue
+		}
+		if v := values[test.n]; v.Value != test.value || v.Lang != test.lang {
+			t.Errorf("%s: Result should have been \"%v\"@%s, but it was \"%v\"@%s", test.property, test.value, test.lang, v.Value, v.Lang)
+		}
+	}
+
+	if brand := item.Properties["brand"][0].(*Item); brand.Values("name")[0].Lang != "en" {
+		t.Errorf("Result should have been \"en\", but it was \"%s\"", brand.Values("name")[0].Lang)
+	}
+}
+
+func TestLangDefault(t *testing.T) {
+	html := `<div itemscope><span itemprop="name">Anvil</span></div>`
+	u, _ := url.Parse("http://example.com/")
+
+	var testTable = []struct {
+		html     string
+		opts     []Option
+		expected string
+	}{
+		{html, nil, ""},
+		{html, []Option{WithLanguage("en-GB")}, "en-GB"},
+		{html, []Option{WithLanguage("en, fr")}, ""},
+		{`<meta http-equiv="content-language" content="nl">` + html, []Option{WithLanguage("en")}, "nl"},
+		{`<meta http-equiv="content-language" content="nl, fr">` + html, []Option{WithLanguage("en")}, "en"},
+	}
+
+	for _, test := range testTable {
+		data, err := ParseHTML(strings.NewReader(test.html), "text/html", u, test.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := data.Items[0].Values("name")[0].Lang; result != test.expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
+		}
+	}
+}
+
+func TestLangMalformed(t *testing.T) {
+	html := `<div itemscope itemtype="http://schema.org/Product" lang="en">
+	<span itemprop="name" lang="en US">Anvil</span>
+	<span itemprop="name" lang="en_US">Anvil</span>
+	<span itemprop="name" lang="&quot;en&quot;">Anvil</span>
+	<span itemprop="name" lang="en-US">Anvil</span>
+	<span itemprop="name" lang="1en">Anvil</span>
+</div>`
+
+	data := ParseData(html, t)
+	var langs []string
+	for _, v := range data.Items[0].Values("name") {
+		langs = append(langs, v.Lang)
+	}
+	expected := []string{"", "", "", "en-US", ""}
+	if !reflect.DeepEqual(langs, expected) {
+		t.Errorf("Result should have been \"%q\", but it was \"%q\"", expected, langs)
+	}
+
+	var buf bytes.Buffer
+	if err := data.WriteNTriples(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if result := buf.String(); !strings.Contains(result, `"Anvil" .`) || strings.Contains(result, "@en ") || strings.Contains(result, "@en_") {
+		t.Errorf("Result should have been literals without malformed tags, but it was \"%s\"", result)
+	}
+
+	u, _ := url.Parse("http://example.com/")
+	data, err := ParseHTML(strings.NewReader(`<div itemscope><span itemprop="name">Anvil</span></div>`), "text/html", u, WithLanguage("en US"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := data.Items[0].Values("name")[0].Lang; result != "" {
+		t.Errorf("Result should have been \"\", but it was \"%s\"", result)
+	}
+}
+
+func TestLangHeader(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Language", "pt-BR")
+		w.Write([]byte(`<div itemscope><span itemprop="name">Bigorna</span></div>`))
+	}))
+	defer ts.Close()
+
+	data, err := ParseURL(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := data.Items[0].Values("name")[0].Lang; result != "pt-BR" {
+		t.Errorf("Result should have been \"pt-BR\", but it was \"%s\"", result)
+	}
+}
+
+func TestValuesModified(t *testing.T) {
+	data := ParseData(langSnippet, t)
+	item := data.Items[0]
+	item.Properties["name"] = append(item.Properties["name"], "Yunque")
+
+	for _, v := range item.Values("name") {
+		if v.Lang != "" {
+			t.Errorf("Result should have been no language, but it was \"%s\"", v.Lang)
+		}
+	}
+}
@@ -13,6 +13,10 @@
 		data, err := microdata.ParseHTML(reader, contentType, baseURL)
 		items := data.Items
 
+	Pass a node tree, or a subtree, parsed by golang.org/x/net/html to the
+	ParseNode function.
+		data, err := microdata.ParseNode(node, baseURL)
+
 	Pass an URL to the ParseURL function.
 		data, _ := microdata.ParseURL("http://example.com/blogposting")
 		items := data.Items
@@ -21,19 +25,32 @@
 package microdata
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
-	"golang.org/x/net/html/charset"
+	"golang.org/x/text/transform"
 )
 
 type Microdata struct {
 	Items []*Item `json:"items"`
+
+	// Encoding is the character encoding of the parsed document.
+	Encoding *Encoding `json:"-"`
+
+	// Renames holds the types and property names replaced when parsing with
+	// the WithVocabularyNormalization option.
+	Renames []Rename `json:"-"`
+
+	// Warnings holds the markup anomalies found while parsing.
+	Warnings []Warning `json:"-"`
 }
 
 // addItem adds the item to the items list.
@@ -49,18 +66,35 @@ type Item struct {
 	Types      []string    `json:"type"`
 	Properties PropertyMap `json:"properties"`
 	ID         string      `json:"id,omitempty"`
+
+	// order holds the property names in the order they were first added.
+	order []string
+
+	// details holds the details of the property values, see Values.
+	details map[string][]valueDetails
 }
 
 // addString adds the property, value pair to the properties map. It appends to any
 // existing property.
-func (i *Item) addString(property, value string) {
+func (i *Item) addString(property, value string, details valueDetails) {
+	i.addProperty(property)
 	i.Properties[property] = append(i.Properties[property], value)
+	i.addDetails(property, details)
 }
 
 // addItem adds the property, value pair to the properties map. It appends to any
 // existing property.
 func (i *Item) addItem(property string, value *Item) {
+	i.addProperty(property)
 	i.Properties[property] = append(i.Properties[property], value)
+	i.addDetails(property, valueDetails{})
+}
+
+// addProperty records the property name in the document order of the item.
+func (i *Item) addProperty(property string) {
+	if _, ok := i.Properties[property]; !ok {
+		i.order = append(i.order, property)
+	}
 }
 
 // addType adds the value to the types list.
@@ -81,30 +115,128 @@ type parser struct {
 	data            *Microdata
 	baseURL         *url.URL
 	identifiedNodes map[string]*html.Node
+
+	// encoding is the label of the encoding overriding the detected one.
+	encoding string
+
+	// language is the default language of the document.
+	language string
+
+	// normalization is the set of normalizations of the text values.
+	normalization Normalization
+
+	// htmlMode selects how the HTML of text values is captured.
+	htmlMode HTMLMode
+
+	// normalizeVocabulary enables the normalization of types and property
+	// names.
+	normalizeVocabulary bool
+
+	// strict turns warnings into an error, warned holds the warnings
+	// recorded so far.
+	strict bool
+	warned map[Warning]bool
+
+	// filter selects the items by type.
+	filter *TypeFilter
+
+	// items holds the nested items by element, so that an element read
+	// through the itemref attributes of more than one item is the same item.
+	// reading holds the elements of the items being read.
+	items   map[*html.Node]*Item
+	reading map[*html.Node]bool
+
+	// positions holds the tree order of the elements of the document, first
+	// holds the position of the first element of each property of the items.
+	positions map[*html.Node]int
+	first     map[*Item]map[string]int
+}
+
+// Option configures the parsing of a document.
+type Option func(*parser)
+
+// WithEncoding overrides the encoding given by the content type or declared
+// by the document with the encoding of the given label, e.g. "shift_jis". A
+// byte order mark still takes precedence.
+func WithEncoding(label string) Option {
+	return func(p *parser) {
+		p.encoding = label
+	}
 }
 
 // parse returns the microdata from the parser's node tree.
 func (p *parser) parse() (*Microdata, error) {
 	toplevelNodes := []*html.Node{}
 
-	walkNodes(p.tree, func(n *html.Node) {
-		if _, ok := getAttr("itemscope", n); ok {
-			if _, ok := getAttr("itemprop", n); !ok {
-				toplevelNodes = append(toplevelNodes, n)
+	// The base element and the elements referenced by itemref attributes are
+	// looked up in the whole document, even when the tree is a subtree.
+	root := p.tree
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	baseFound := false
+	languageFound := false
+	referenced := make(map[string]bool)
+	p.positions = make(map[*html.Node]int)
+	p.first = make(map[*Item]map[string]int)
+	walkNodes(root, func(n *html.Node) {
+		p.positions[n] = len(p.positions)
+
+		// The first base element with an href attribute sets the document
+		// base URL.
+		if n.DataAtom == atom.Base && !baseFound {
+			if href, ok := getAttr("href", n); ok {
+				baseFound = true
+				if u, err := p.baseURL.Parse(href); err == nil {
+					p.baseURL = u
+				}
 			}
 		}
 		if id, ok := getAttr("id", n); ok {
 			p.identifiedNodes[id] = n
 		}
+		if !languageFound && n.DataAtom == atom.Meta {
+			languageFound = p.readLanguagePragma(n)
+		}
+		if s, ok := getAttr("itemref", n); ok {
+			for _, id := range strings.Fields(s) {
+				referenced[id] = true
+			}
+		}
+	})
+
+	walkNodes(p.tree, func(n *html.Node) {
+		_, hasScope := getAttr("itemscope", n)
+		_, hasProp := getAttr("itemprop", n)
+		if hasScope && !hasProp {
+			toplevelNodes = append(toplevelNodes, n)
+		}
+		if hasProp {
+			p.checkItemProp(n, referenced)
+		}
 	})
 
 	for _, node := range toplevelNodes {
+		if p.skipItem(node) {
+			continue
+		}
 		item := NewItem()
 		p.data.addItem(item)
 		p.readAttr(item, node)
 		p.readItem(item, node, true)
+		p.sortProperties(item)
 	}
 
+	if p.normalizeVocabulary {
+		p.data.Renames = p.data.NormalizeVocabulary()
+	}
+	if p.filter != nil {
+		p.data = p.filter.Apply(p.data)
+	}
+	if p.strict && len(p.data.Warnings) > 0 {
+		return nil, &StrictError{Warnings: p.data.Warnings}
+	}
 	return p.data, nil
 }
 
@@ -113,25 +245,41 @@ func (p *parser) parse() (*Microdata, error) {
 func (p *parser) readItem(item *Item, node *html.Node, isToplevel bool) {
 	itemprops, hasProp := getAttr("itemprop", node)
 	_, hasScope := getAttr("itemscope", node)
+	if hasProp && strings.TrimSpace(itemprops) == "" {
+		p.warn(EmptyItemProp, node, "itemprop has no property names")
+	}
 
 	switch {
 	case hasScope && hasProp:
-		subItem := NewItem()
-		p.readAttr(subItem, node)
+		if p.reading[node] {
+			// The item holds itself through itemref, leave it out to keep
+			// the items a tree.
+			return
+		}
+		subItem, ok := p.items[node]
+		if !ok {
+			subItem = p.readSubItem(node)
+		}
 		for _, propName := range strings.Split(itemprops, " ") {
 			if len(propName) > 0 {
+				p.addPosition(item, propName, node)
 				item.addItem(propName, subItem)
 			}
 		}
-		for c := node.FirstChild; c != nil; c = c.NextSibling {
-			p.readItem(subItem, c, false)
-		}
 		return
 	case !hasScope && hasProp:
-		if s := p.getValue(node); len(s) > 0 {
+		if s, kind := p.getValue(node); len(s) > 0 {
+			details := valueDetails{kind: kind}
+			if kind.text() {
+				details.lang = p.lang(node)
+			}
+			if kind == elementText && p.htmlMode != NoHTML {
+				details.html = p.innerHTML(node)
+			}
 			for _, propName := range strings.Split(itemprops, " ") {
 				if len(propName) > 0 {
-					item.addString(propName, s)
+					p.addPosition(item, propName, node)
+					item.addString(propName, s, details)
 				}
 			}
 		}
@@ -144,6 +292,49 @@ func (p *parser) readItem(item *Item, node *html.Node, isToplevel bool) {
 	}
 }
 
+// readSubItem returns the item of the element, an itemscope element with an
+// itemprop attribute, and records it for the other items holding it.
+func (p *parser) readSubItem(node *html.Node) *Item {
+	if p.items == nil {
+		p.items = make(map[*html.Node]*Item)
+		p.reading = make(map[*html.Node]bool)
+	}
+	item := NewItem()
+	p.reading[node] = true
+	p.readAttr(item, node)
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		p.readItem(item, c, false)
+	}
+	delete(p.reading, node)
+	p.sortProperties(item)
+	p.items[node] = item
+	return item
+}
+
+// addPosition records the tree position of the element of a property of the
+// item, when it's the first element of the property.
+func (p *parser) addPosition(item *Item, name string, node *html.Node) {
+	first, ok := p.first[item]
+	if !ok {
+		first = make(map[string]int)
+		p.first[item] = first
+	}
+	if pos, ok := first[name]; !ok || p.positions[node] < pos {
+		first[name] = p.positions[node]
+	}
+}
+
+// sortProperties sorts the property names of the item in tree order of their
+// first elements, like the properties of the microdata specification. The
+// properties read through itemref follow the properties of the item's own
+// elements which precede them in the document.
+func (p *parser) sortProperties(item *Item) {
+	first := p.first[item]
+	sort.SliceStable(item.order, func(a, b int) bool {
+		return first[item.order[a]] < first[item.order[b]]
+	})
+}
+
 // readAttr applies relevant attributes from the given node to the given item.
 func (p *parser) readAttr(item *Item, node *html.Node) {
 	if s, ok := getAttr("itemtype", node); ok {
@@ -156,8 +347,12 @@ func (p *parser) readAttr(item *Item, node *html.Node) {
 		if s, ok := getAttr("itemid", node); ok {
 			if u, err := p.baseURL.Parse(s); err == nil {
 				item.ID = u.String()
+			} else {
+				p.warn(InvalidItemID, node, "itemid %q is not a valid URL", s)
 			}
 		}
+	} else if _, ok := getAttr("itemid", node); ok {
+		p.warn(ItemIDWithoutType, node, "itemid on an item without itemtype")
 	}
 
 	if s, ok := getAttr("itemref", node); ok {
@@ -165,20 +360,51 @@ func (p *parser) readAttr(item *Item, node *html.Node) {
 			if len(itemref) > 0 {
 				if n, ok := p.identifiedNodes[itemref]; ok {
 					p.readItem(item, n, false)
+				} else {
+					p.warn(MissingItemRef, node, "itemref %q references no element", itemref)
 				}
 			}
 		}
 	}
 }
 
-// getValue returns the value of the property, value pair in the given node.
-func (p *parser) getValue(node *html.Node) string {
+// valueKind is the kind of a property value.
+type valueKind int
+
+const (
+	// unknownValue is the kind of the values which weren't parsed.
+	unknownValue valueKind = iota
+
+	// urlValue is the absolute URL of a src or href attribute.
+	urlValue
+
+	// dataValue is a machine-readable value, like the datetime attribute of
+	// a time element.
+	dataValue
+
+	// attributeText is the text of a content attribute.
+	attributeText
+
+	// elementText is the text of an element.
+	elementText
+)
+
+// text reports whether the kind is the text of an element or an attribute.
+func (k valueKind) text() bool {
+	return k == attributeText || k == elementText
+}
+
+// getValue returns the value of the property, value pair in the given node and
+// its kind.
+func (p *parser) getValue(node *html.Node) (string, valueKind) {
 	var propValue string
+	kind := dataValue
 
 	switch node.DataAtom {
 	case atom.Meta:
 		if value, ok := getAttr("content", node); ok {
 			propValue = value
+			kind = attributeText
 		}
 	case atom.Audio, atom.Embed, atom.Iframe, atom.Img, atom.Source, atom.Track, atom.Video:
 		if value, ok := getAttr("src", node); ok {
@@ -186,12 +412,14 @@ func (p *parser) getValue(node *html.Node) string {
 				propValue = u.String()
 			}
 		}
+		kind = urlValue
 	case atom.A, atom.Area, atom.Link:
 		if value, ok := getAttr("href", node); ok {
 			if u, err := p.baseURL.Parse(value); err == nil {
 				propValue = u.String()
 			}
 		}
+		kind = urlValue
 	case atom.Data, atom.Meter:
 		if value, ok := getAttr("value", node); ok {
 			propValue = value
@@ -204,6 +432,13 @@ func (p *parser) getValue(node *html.Node) string {
 		// The "content" attribute can be found on other tags besides the meta tag.
 		if value, ok := getAttr("content", node); ok {
 			propValue = value
+			kind = attributeText
+			break
+		}
+
+		kind = elementText
+		if p.normalization&RenderedText != 0 {
+			propValue = renderedText(node)
 			break
 		}
 
@@ -216,27 +451,55 @@ func (p *parser) getValue(node *html.Node) string {
 		propValue = buf.String()
 	}
 
-	return propValue
+	if kind.text() {
+		propValue = p.normalization.normalize(propValue)
+	}
+	return propValue, kind
 }
 
-// newParser returns a parser that converts the content of r to UTF-8 based on the content type of r.
-func newParser(r io.Reader, contentType string, baseURL *url.URL) (*parser, error) {
-	r, err := charset.NewReader(r, contentType)
-	if err != nil {
+// WithLanguage sets the default language of the text values, like a
+// Content-Language header. The lang attributes and a Content-Language meta
+// element of the document take precedence.
+func WithLanguage(tag string) Option {
+	return func(p *parser) {
+		p.language = contentLanguage(tag)
+	}
+}
+
+// newParser returns a parser that converts the content of r to UTF-8. The
+// encoding of r is determined from its byte order mark, the options, the
+// content type and the meta elements of the document, in that order.
+func newParser(r io.Reader, contentType string, baseURL *url.URL, opts ...Option) (*parser, error) {
+	p := &parser{
+		data:            &Microdata{},
+		baseURL:         baseURL,
+		identifiedNodes: make(map[string]*html.Node),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	// Peek reads until it has sniffLen bytes, the end of the document or an
+	// error, whatever the number of bytes r returns per Read. The peeked bytes
+	// are read again by the HTML parser.
+	br := bufio.NewReaderSize(r, sniffLen)
+	prefix, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
 		return nil, err
 	}
 
-	tree, err := html.Parse(r)
+	e, enc, bomLen, err := determineEncoding(prefix, contentType, p.encoding)
 	if err != nil {
 		return nil, err
 	}
+	br.Discard(bomLen)
+	p.data.Encoding = &enc
 
-	return &parser{
-		tree:            tree,
-		data:            &Microdata{},
-		baseURL:         baseURL,
-		identifiedNodes: make(map[string]*html.Node),
-	}, nil
+	p.tree, err = html.Parse(transform.NewReader(br, e.NewDecoder()))
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
 // getAttr returns the value associated with the given attribute from the given node.
@@ -261,30 +524,51 @@ func walkNodes(n *html.Node, f func(*html.Node)) {
 
 // ParseHTML parses the HTML document available in the given reader and returns
 // the microdata. The given url is used to resolve the URLs in the
-// attributes. The given contentType is used convert the content of r to UTF-8.
-// When the given contentType is equal to "", the content type will be detected
-// using `http.DetectContentType`.
-func ParseHTML(r io.Reader, contentType string, u *url.URL) (*Microdata, error) {
-	if contentType == "" {
-		b := make([]byte, 512)
-		_, err := r.Read(b)
-		if err != nil {
-			return nil, err
-		}
-		contentType = http.DetectContentType(b)
-		r = io.MultiReader(bytes.NewReader(b), r)
-	}
-
-	p, err := newParser(r, contentType, u)
+// attributes. The charset of the given contentType is used to convert the
+// content of r to UTF-8, unless r starts with a byte order mark or an option
+// overrides it. When contentType has no charset, the encoding declared by the
+// document is used, or UTF-8 when the document is valid UTF-8, or
+// windows-1252. The encoding used is reported in the Encoding field of the
+// returned microdata.
+func ParseHTML(r io.Reader, contentType string, u *url.URL, opts ...Option) (*Microdata, error) {
+	p, err := newParser(r, contentType, u, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return p.parse()
 }
 
+// ParseNode returns the microdata of the given node tree, parsed by the
+// golang.org/x/net/html package, e.g. a node of a goquery selection. The given
+// url is used to resolve the URLs in the attributes. The options are applied
+// like the options of ParseHTML; WithEncoding has no effect as the node tree is
+// already decoded.
+//
+// The node may be a subtree of a document. Only the top-level items in the
+// subtree are returned, but the base element and the elements referenced by
+// itemref attributes are looked up in the whole document of the node.
+func ParseNode(node *html.Node, u *url.URL, opts ...Option) (*Microdata, error) {
+	if node == nil {
+		return nil, errors.New("microdata: nil node")
+	}
+
+	p := &parser{
+		tree:            node,
+		data:            &Microdata{},
+		baseURL:         u,
+		identifiedNodes: make(map[string]*html.Node),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p.parse()
+}
+
 // ParseURL parses the HTML document available at the given URL and returns the
-// microdata.
-func ParseURL(urlStr string) (*Microdata, error) {
+// microdata. The options and the Content-Type header of the response are used
+// like the options and the content type of ParseHTML. The Content-Language
+// header sets the default language, see WithLanguage.
+func ParseURL(urlStr string, opts ...Option) (*Microdata, error) {
 	var data *Microdata
 
 	u, err := url.Parse(urlStr)
@@ -296,10 +580,12 @@ func ParseURL(urlStr string) (*Microdata, error) {
 	if err != nil {
 		return data, err
 	}
+	defer resp.Body.Close()
 
 	contentType := resp.Header.Get("Content-Type")
 
-	p, err := newParser(resp.Body, contentType, u)
+	opts = append([]Option{WithLanguage(resp.Header.Get("Content-Language"))}, opts...)
+	p, err := newParser(resp.Body, contentType, u, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -10,8 +10,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
+
+	"golang.org/x/net/html"
 )
 
 func TestParseItemScope(t *testing.T) {
@@ -71,6 +75,53 @@ func TestParseItemRef(t *testing.T) {
 	}
 }
 
+func TestParseItemRefOrder(t *testing.T) {
+	html := `
+		<p id="before"><span itemprop="brand">ACME</span></p>
+		<div itemscope itemref="after before"><span itemprop="name">Anvil</span><span itemprop="color">red</span></div>
+		<p id="after"><span itemprop="http://ex.com/p">x</span><span itemprop="color">black</span></p>`
+
+	data := ParseData(html, t)
+	expected := []string{"brand", "name", "color", "http://ex.com/p"}
+	if result := data.Items[0].PropertyNames(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", expected, result)
+	}
+}
+
+func TestParseItemRefShared(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://schema.org/Product" itemref="seller"><span itemprop="name">Anvil</span></div>
+		<div itemscope itemtype="http://schema.org/Product" itemref="seller"><span itemprop="name">Hammer</span></div>
+		<div id="seller" itemprop="seller" itemscope itemtype="http://schema.org/Organization"><span itemprop="name">ACME</span></div>`
+
+	data := ParseData(html, t)
+	if len(data.Items) != 2 {
+		t.Fatalf("Result should have been 2 items, but it was %d", len(data.Items))
+	}
+	first, second := data.Items[0].Properties["seller"][0], data.Items[1].Properties["seller"][0]
+	if first != second {
+		t.Errorf("Result should have been the same seller, but it was \"%v\" and \"%v\"", first, second)
+	}
+}
+
+func TestParseItemRefCycle(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://schema.org/Person" itemref="a"></div>
+		<div id="a" itemprop="knows" itemscope itemtype="http://schema.org/Person" itemref="b"><span itemprop="name">A</span></div>
+		<div id="b" itemprop="knows" itemscope itemtype="http://schema.org/Person" itemref="a"><span itemprop="name">B</span></div>`
+
+	data := ParseData(html, t)
+	a := data.Items[0].Properties["knows"][0].(*Item)
+	b := a.Properties["knows"][0].(*Item)
+	if _, ok := b.Properties["knows"]; ok {
+		t.Errorf("Result should have been no knows property, but it was \"%v\"", b.Properties["knows"])
+	}
+	expected := `{"items":[{"type":["http://schema.org/Person"],"properties":{"knows":[{"type":["http://schema.org/Person"],"properties":{"knows":[{"type":["http://schema.org/Person"],"properties":{"name":["B"]}}],"name":["A"]}}]}}]}`
+	if result := string(data.Canonical()); result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
 func TestParseItemProp(t *testing.T) {
 	html := `
 		<div itemscope itemtype="http://example.com/Person">
@@ -281,6 +332,145 @@ func TestParseHTML(t *testing.T) {
 	}
 }
 
+func TestParseHTMLShortReads(t *testing.T) {
+	doc := `<div itemscope itemtype="http://example.com/Person"><span itemprop="name">Penelope</span></div>`
+	long := doc + strings.Repeat("<p>filler</p>", 200)
+	u, _ := url.Parse("http://example.com/")
+
+	var testTable = []struct {
+		name   string
+		reader func() io.Reader
+	}{
+		{"tiny", func() io.Reader { return strings.NewReader(doc) }},
+		{"one byte", func() io.Reader { return iotest.OneByteReader(strings.NewReader(doc)) }},
+		{"data and EOF", func() io.Reader { return iotest.DataErrReader(strings.NewReader(doc)) }},
+		{"half long", func() io.Reader { return iotest.HalfReader(strings.NewReader(long)) }},
+		{"one byte long", func() io.Reader { return iotest.OneByteReader(strings.NewReader(long)) }},
+		{"data and EOF long", func() io.Reader { return iotest.DataErrReader(strings.NewReader(long)) }},
+	}
+
+	for _, test := range testTable {
+		for _, contentType := range []string{"", "text/html; charset=utf-8"} {
+			data, err := ParseHTML(test.reader(), contentType, u)
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+				continue
+			}
+			if len(data.Items) != 1 {
+				t.Errorf("%s: Result should have been 1 item, but it was %d", test.name, len(data.Items))
+				continue
+			}
+			if result := data.Items[0].Properties["name"][0]; result != "Penelope" {
+				t.Errorf("%s: Result should have been \"Penelope\", but it was \"%v\"", test.name, result)
+			}
+		}
+	}
+}
+
+func TestParseHTMLEmpty(t *testing.T) {
+	u, _ := url.Parse("http://example.com/")
+	for _, r := range []io.Reader{strings.NewReader(""), iotest.DataErrReader(strings.NewReader(""))} {
+		data, err := ParseHTML(r, "", u)
+		if err != nil {
+			t.Errorf("Result should have been nil, but it was \"%s\"", err)
+			continue
+		}
+		if len(data.Items) != 0 {
+			t.Errorf("Result should have been no items, but it was %d", len(data.Items))
+		}
+	}
+}
+
+func TestParseHTMLReadError(t *testing.T) {
+	u, _ := url.Parse("http://example.com/")
+	long := strings.Repeat("<p>filler</p>", 200)
+	for _, r := range []io.Reader{
+		iotest.ErrReader(iotest.ErrTimeout),
+		iotest.TimeoutReader(strings.NewReader(long)),
+		io.MultiReader(strings.NewReader("<p>"), iotest.ErrReader(iotest.ErrTimeout)),
+	} {
+		_, err := ParseHTML(r, "", u)
+		if err != iotest.ErrTimeout {
+			t.Errorf("Result should have been \"%s\", but it was \"%v\"", iotest.ErrTimeout, err)
+		}
+	}
+}
+
+func TestParseNode(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><head><base href="http://shop.example.com/"></head><body>
+		<p id="brand">Brand: <span itemprop="brand">ACME</span></p>
+		<section id="a">
+			<div itemscope itemtype="http://schema.org/Product" itemref="brand"><span itemprop="name">Anvil</span></div>
+		</section>
+		<section id="b">
+			<div itemscope itemtype="http://schema.org/Product" itemref="brand">
+				<span itemprop="name">Rocket</span>
+				<a itemprop="url" href="rocket">Rocket</a>
+			</div>
+		</section>
+	</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var section *html.Node
+	walkNodes(doc, func(n *html.Node) {
+		if id, _ := getAttr("id", n); id == "b" {
+			section = n
+		}
+	})
+
+	u, _ := url.Parse("http://example.com/")
+	data, err := ParseNode(section, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Items) != 1 {
+		t.Fatalf("Result should have been 1 item, but it was %d", len(data.Items))
+	}
+
+	var testTable = []struct {
+		result   interface{}
+		expected interface{}
+	}{
+		{data.Items[0].Properties["name"][0], "Rocket"},
+		{data.Items[0].Properties["brand"][0], "ACME"},
+		{data.Items[0].Properties["url"][0], "http://shop.example.com/rocket"},
+	}
+	for _, test := range testTable {
+		if test.result != test.expected {
+			t.Errorf("Result should have been \"%v\", but it was \"%v\"", test.expected, test.result)
+		}
+	}
+
+	data, err = ParseNode(doc, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Items) != 2 {
+		t.Errorf("Result should have been 2 items, but it was %d", len(data.Items))
+	}
+
+	data, err = ParseNode(section, u, WithLanguage("en"), WithTypeFilter(&TypeFilter{Types: []string{"Product"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Items) != 1 || data.Items[0].Values("name")[0].Lang != "en" {
+		t.Errorf("Result should have been 1 item in en, but it was \"%v\"", data.Items)
+	}
+	data, err = ParseNode(doc, u, WithTypeFilter(&TypeFilter{Exclude: []string{"Product"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Items) != 0 {
+		t.Errorf("Result should have been 0 items, but it was %d", len(data.Items))
+	}
+
+	if _, err := ParseNode(nil, u); err == nil {
+		t.Error("Result should have been an error, but it was nil")
+	}
+}
+
 func TestParseURL(t *testing.T) {
 	html := `
 		<div itemscope itemtype="http://example.com/Person">
@@ -329,6 +519,44 @@ func TestNestedItems(t *testing.T) {
 	}
 }
 
+func TestParseBaseElement(t *testing.T) {
+	html := `
+		<html>
+			<head>
+				<base href="http://shop.example.com/catalog/">
+				<base href="http://other.example.com/">
+			</head>
+			<body>
+				<div itemscope itemtype="http://example.com/Product" itemid="products/1">
+					<a itemprop="url" href="kettle">Kettle</a>
+					<img itemprop="image" src="/images/kettle.jpeg">
+				</div>
+			</body>
+		</html>`
+
+	data := ParseData(html, t)
+
+	var testTable = []struct {
+		propName string
+		expected string
+	}{
+		{"url", "http://shop.example.com/catalog/kettle"},
+		{"image", "http://shop.example.com/images/kettle.jpeg"},
+	}
+
+	for _, test := range testTable {
+		if result := data.Items[0].Properties[test.propName][0].(string); result != test.expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
+		}
+	}
+
+	result := data.Items[0].ID
+	expected := "http://shop.example.com/catalog/products/1"
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
 func ParseData(html string, t *testing.T) *Microdata {
 	r := strings.NewReader(html)
 	u, _ := url.Parse("http://example.com")
@@ -0,0 +1,156 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// MiddlewareConfig configures the middleware returned by Middleware.
+type MiddlewareConfig struct {
+	// Report, when set, is called with the extraction result of every HTML
+	// response, e.g. to log it.
+	Report func(*Report)
+
+	// Header adds the X-Microdata-Items response header with the number of
+	// top-level items and, with a profile, the X-Microdata-Violations header
+	// with the number of violations.
+	Header bool
+
+	// Profile, when set, validates the extracted items.
+	Profile Profile
+
+	// Strict replaces responses which violate the profile with a 500 Internal
+	// Server Error listing the violations. Use it in development.
+	Strict bool
+
+	// MaxBody is the maximum size in bytes of a buffered response. Larger
+	// responses are passed through without extraction. The default is 10 MB.
+	MaxBody int64
+}
+
+// Report is the extraction result of a response.
+type Report struct {
+	Request    *http.Request
+	Data       *Microdata
+	Violations []Violation
+	Err        error
+}
+
+// Middleware returns a handler which buffers the successful HTML responses of
+// the next handler and extracts their microdata, using the request URL as the
+// base URL. Other responses are passed through as is. Responses of the next
+// handler can't be flushed while they're buffered.
+func Middleware(next http.Handler, config MiddlewareConfig) http.Handler {
+	if config.MaxBody <= 0 {
+		config.MaxBody = 10 << 20
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bw := &bufferedWriter{ResponseWriter: w, max: config.MaxBody, head: r.Method == http.MethodHead}
+		next.ServeHTTP(bw, r)
+		if !bw.wroteHeader {
+			bw.WriteHeader(http.StatusOK)
+		}
+		if bw.passthrough {
+			return
+		}
+
+		report := &Report{Request: r}
+		report.Data, report.Err = ParseHTML(bytes.NewReader(bw.buf.Bytes()), w.Header().Get("Content-Type"), requestURL(r), WithLanguage(w.Header().Get("Content-Language")))
+		if report.Err == nil && config.Profile != nil {
+			report.Violations = config.Profile.Validate(report.Data)
+		}
+		if config.Report != nil {
+			config.Report(report)
+		}
+
+		if report.Err == nil && config.Header {
+			w.Header().Set("X-Microdata-Items", strconv.Itoa(len(report.Data.Items)))
+			if config.Profile != nil {
+				w.Header().Set("X-Microdata-Violations", strconv.Itoa(len(report.Violations)))
+			}
+		}
+
+		if config.Strict && len(report.Violations) > 0 {
+			w.Header().Del("Content-Length")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintln(w, "microdata: the response violates the validation profile:")
+			for _, v := range report.Violations {
+				fmt.Fprintln(w, v.Error())
+			}
+			return
+		}
+
+		w.WriteHeader(bw.code)
+		w.Write(bw.buf.Bytes())
+	})
+}
+
+// requestURL returns the absolute URL of the request.
+func requestURL(r *http.Request) *url.URL {
+	u := *r.URL
+	u.Host = r.Host
+	u.Scheme = "http"
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	return &u
+}
+
+// bufferedWriter buffers successful HTML responses. It passes through other
+// responses and responses which grow larger than max bytes.
+type bufferedWriter struct {
+	http.ResponseWriter
+	buf         bytes.Buffer
+	code        int
+	max         int64
+	head        bool
+	wroteHeader bool
+	passthrough bool
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.code = code
+
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	isHTML := mediaType == "text/html" || mediaType == "application/xhtml+xml"
+	if w.head || !isHTML || code < 200 || code > 299 {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+
+	if int64(w.buf.Len()+len(b)) > w.max {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(w.code)
+		if _, err := w.ResponseWriter.Write(w.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		w.buf.Reset()
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
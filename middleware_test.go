@@ -0,0 +1,106 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://schema.org/Product">
+			<a itemprop="url" href="kettle">Kettle</a>
+		</div>`
+
+	var report *Report
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, html)
+	}), MiddlewareConfig{
+		Report: func(r *Report) { report = r },
+		Header: true,
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "http://shop.example.com/products/", nil))
+
+	if w.Body.String() != html {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", html, w.Body.String())
+	}
+	if result, expected := w.Header().Get("X-Microdata-Items"), "1"; result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+
+	if report == nil || report.Err != nil {
+		t.Fatalf("Result should have been a report without error, but it was %v", report)
+	}
+	result := report.Data.Items[0].Properties["url"][0].(string)
+	expected := "http://shop.example.com/products/kettle"
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestMiddlewareStrict(t *testing.T) {
+	html := `<div itemscope itemtype="http://schema.org/Offer"><span itemprop="price">1.00</span></div>`
+
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, html)
+	}), MiddlewareConfig{Profile: SchemaOrg, Strict: true, Header: true})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/offer", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Result should have been %d, but it was %d", http.StatusInternalServerError, w.Code)
+	}
+	if result, expected := w.Header().Get("X-Microdata-Violations"), "1"; result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+	if !strings.Contains(w.Body.String(), `missing required property "priceCurrency"`) {
+		t.Errorf("Result should have listed the violation, but it was \"%s\"", w.Body.String())
+	}
+}
+
+func TestMiddlewarePassthrough(t *testing.T) {
+	var testTable = []struct {
+		name        string
+		contentType string
+		code        int
+		maxBody     int64
+	}{
+		{"json", "application/json", http.StatusOK, 0},
+		{"not found", "text/html", http.StatusNotFound, 0},
+		{"too large", "text/html", http.StatusOK, 8},
+	}
+
+	body := `<div itemscope itemtype="http://schema.org/Offer"></div>`
+	for _, test := range testTable {
+		reported := false
+		h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", test.contentType)
+			w.WriteHeader(test.code)
+			io.WriteString(w, body)
+		}), MiddlewareConfig{
+			Report:  func(*Report) { reported = true },
+			Profile: SchemaOrg,
+			Strict:  true,
+			MaxBody: test.maxBody,
+		})
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+		if reported {
+			t.Errorf("%s: Result should not have been reported", test.name)
+		}
+		if w.Code != test.code || w.Body.String() != body {
+			t.Errorf("%s: Result should have been passed through, but it was %d \"%s\"", test.name, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization is a set of normalizations of text values. Text values are
+// the values of meta elements, content attributes and the text of elements,
+// URLs and machine-readable values aren't normalized. By default text values
+// are extracted as specified, concatenating the text of the element with the
+// whitespace of the markup.
+type Normalization uint
+
+// The normalizations, applied in this order.
+const (
+	// RenderedText extracts the text of elements as rendered: the text of
+	// script, style, template and hidden elements is left out and block
+	// elements and line breaks are separated by a space.
+	RenderedText Normalization = 1 << iota
+
+	// StripZeroWidth removes zero width spaces, joiners and non-joiners, word
+	// joiners and byte order marks.
+	StripZeroWidth
+
+	// NFC converts the text to Unicode Normalization Form C.
+	NFC
+
+	// CollapseWhitespace replaces every run of whitespace by a single space.
+	CollapseWhitespace
+
+	// TrimSpace removes leading and trailing whitespace.
+	TrimSpace
+
+	// AllNormalizations is the set of all normalizations.
+	AllNormalizations = RenderedText | StripZeroWidth | NFC | CollapseWhitespace | TrimSpace
+)
+
+// normalizationNames are the names of the normalizations, see
+// ParseNormalization.
+var normalizationNames = []struct {
+	name string
+	n    Normalization
+}{
+	{"rendered", RenderedText},
+	{"zero-width", StripZeroWidth},
+	{"nfc", NFC},
+	{"collapse", CollapseWhitespace},
+	{"trim", TrimSpace},
+	{"all", AllNormalizations},
+}
+
+// ParseNormalization returns the set of normalizations of a comma separated
+// list of names: rendered, zero-width, nfc, collapse, trim and all.
+func ParseNormalization(s string) (Normalization, error) {
+	var n Normalization
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, nn := range normalizationNames {
+			if strings.EqualFold(name, nn.name) {
+				n |= nn.n
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("microdata: unknown normalization %q", name)
+		}
+	}
+	return n, nil
+}
+
+// String returns the comma separated names of the normalizations.
+func (n Normalization) String() string {
+	var names []string
+	for _, nn := range normalizationNames {
+		if nn.n != AllNormalizations && n&nn.n != 0 {
+			names = append(names, nn.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// WithNormalization sets the normalizations of the text values.
+func WithNormalization(n Normalization) Option {
+	return func(p *parser) {
+		p.normalization = n
+	}
+}
+
+// normalize applies the normalizations, but RenderedText, to the text.
+func (n Normalization) normalize(s string) string {
+	if n&StripZeroWidth != 0 {
+		s = strings.Map(func(r rune) rune {
+			switch r {
+			case '\u200b', '\u200c', '\u200d', '\u2060', '\ufeff':
+				return -1
+			}
+			return r
+		}, s)
+	}
+	if n&NFC != 0 {
+		s = norm.NFC.String(s)
+	}
+	if n&CollapseWhitespace != 0 {
+		s = collapseWhitespace(s)
+	}
+	if n&TrimSpace != 0 {
+		s = strings.TrimSpace(s)
+	}
+	return s
+}
+
+// collapseWhitespace replaces every run of whitespace in s by a single space.
+func collapseWhitespace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// blockElements are the elements rendered as blocks, separated from the text
+// around them.
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Caption: true, atom.Dd: true, atom.Details: true, atom.Dialog: true,
+	atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Fieldset: true,
+	atom.Figcaption: true, atom.Figure: true, atom.Footer: true, atom.Form: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true,
+	atom.H6: true, atom.Header: true, atom.Hr: true, atom.Li: true, atom.Main: true,
+	atom.Nav: true, atom.Ol: true, atom.P: true, atom.Pre: true, atom.Section: true,
+	atom.Summary: true, atom.Table: true, atom.Td: true, atom.Th: true, atom.Tr: true,
+	atom.Ul: true,
+}
+
+// renderedText returns the text of the children of the node as rendered, see
+// RenderedText.
+func renderedText(node *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			switch n.DataAtom {
+			case atom.Script, atom.Style, atom.Template, atom.Noscript:
+				return
+			case atom.Br:
+				b.WriteByte(' ')
+				return
+			}
+			if _, ok := getAttr("hidden", n); ok {
+				return
+			}
+		}
+
+		block := n.Type == html.ElementNode && blockElements[n.DataAtom]
+		if block {
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			b.WriteByte(' ')
+		}
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		walk(c)
+	}
+	return b.String()
+}
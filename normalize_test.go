@@ -0,0 +1,81 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var normalizeSnippet = `<div itemscope itemtype="http://schema.org/Article">
+	<h1 itemprop="name">
+		Cafe` + "\u0301 \u200b" + `Review
+	</h1>
+	<div itemprop="articleBody"><p>First<br>line.</p><script>var x = 1;</script><style>p {}</style><p hidden>Hidden</p><p>Second</p></div>
+	<meta itemprop="keywords" content="  coffee,   tea ">
+	<a itemprop="url" href="/review">Review</a>
+</div>`
+
+func TestNormalization(t *testing.T) {
+	u, _ := url.Parse("http://example.com/")
+
+	var testTable = []struct {
+		normalization Normalization
+		property      string
+		expected      string
+	}{
+		{0, "name", "\n\t\tCafe\u0301 \u200bReview\n\t"},
+		{0, "articleBody", "Firstline.var x = 1;p {}HiddenSecond"},
+		{TrimSpace, "name", "Cafe\u0301 \u200bReview"},
+		{CollapseWhitespace, "name", " Cafe\u0301 \u200bReview "},
+		{CollapseWhitespace | TrimSpace, "keywords", "coffee, tea"},
+		{StripZeroWidth | TrimSpace, "name", "Cafe\u0301 Review"},
+		{NFC | TrimSpace, "name", "Caf\u00e9 \u200bReview"},
+		{RenderedText, "articleBody", " First line.  Second "},
+		{AllNormalizations, "name", "Caf\u00e9 Review"},
+		{AllNormalizations, "articleBody", "First line. Second"},
+		{AllNormalizations, "url", "http://example.com/review"},
+	}
+
+	for _, test := range testTable {
+		data, err := ParseHTML(strings.NewReader(normalizeSnippet), "text/html", u, WithNormalization(test.normalization))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := data.Items[0].Properties[test.property][0]; result != test.expected {
+			t.Errorf("%s (%v): Result should have been %q, but it was %q", test.property, test.normalization, test.expected, result)
+		}
+	}
+}
+
+func TestParseNormalization(t *testing.T) {
+	var testTable = []struct {
+		s        string
+		expected Normalization
+	}{
+		{"", 0},
+		{"trim", TrimSpace},
+		{"collapse, TRIM", CollapseWhitespace | TrimSpace},
+		{"all", AllNormalizations},
+	}
+
+	for _, test := range testTable {
+		result, err := ParseNormalization(test.s)
+		if err != nil {
+			t.Errorf("%q: %v", test.s, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("Result should have been \"%v\", but it was \"%v\"", test.expected, result)
+		}
+	}
+
+	if _, err := ParseNormalization("trim,squash"); err == nil {
+		t.Error("Result should have been an error, but it was nil")
+	}
+	if result := AllNormalizations.String(); result != "rendered,zero-width,nfc,collapse,trim" {
+		t.Errorf("Result should have been \"rendered,zero-width,nfc,collapse,trim\", but it was \"%s\"", result)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const rdfType = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+
+// JSONLD returns the microdata as a JSON-LD document, expanding property names
+// with the DefaultRegistry, see Registry.JSONLD.
+func (m *Microdata) JSONLD() map[string]interface{} {
+	return DefaultRegistry.JSONLD(m)
+}
+
+// JSONLD returns the microdata as a JSON-LD document. Items become node
+// objects in the "@graph" of the document with their itemid as "@id" and
+// their types as "@type". Property names are expanded to IRIs, see Expand;
+// properties which can't be expanded are left out. URL values become node
+// references and text values with a language become value objects with a
+// "@language". An item held by more than one item, e.g. through itemref, is
+// written once and referenced by its "@id" elsewhere; it's given a blank node
+// identifier when it has no itemid.
+func (r Registry) JSONLD(m *Microdata) map[string]interface{} {
+	j := &jsonld{r: r, ids: sharedItemIDs(m), written: make(map[*Item]bool)}
+	graph := make([]interface{}, 0, len(m.Items))
+	for _, item := range m.Items {
+		graph = append(graph, j.node(item, nil))
+	}
+	return map[string]interface{}{"@graph": graph}
+}
+
+// jsonld holds the state of the JSON-LD mapping of a document.
+type jsonld struct {
+	r Registry
+
+	// ids holds the "@id" of the items held more than once, written holds
+	// the items already written.
+	ids     map[*Item]string
+	written map[*Item]bool
+}
+
+// sharedItemIDs returns the "@id" of the items held more than once in the
+// microdata: their itemid or a blank node identifier.
+func sharedItemIDs(m *Microdata) map[*Item]string {
+	refs := make(map[*Item]int)
+	var count func(item *Item)
+	count = func(item *Item) {
+		refs[item]++
+		if refs[item] > 1 {
+			return
+		}
+		for _, values := range item.Properties {
+			for _, v := range values {
+				if sub, ok := v.(*Item); ok {
+					count(sub)
+				}
+			}
+		}
+	}
+	for _, item := range m.Items {
+		count(item)
+	}
+
+	ids := make(map[*Item]string)
+	blank := 0
+	var visit func(item *Item)
+	visit = func(item *Item) {
+		if _, ok := ids[item]; ok {
+			return
+		}
+		if refs[item] > 1 {
+			ids[item] = item.ID
+			if item.ID == "" {
+				ids[item] = fmt.Sprintf("_:b%d", blank)
+				blank++
+			}
+		}
+		for _, name := range item.PropertyNames() {
+			for _, v := range item.Properties[name] {
+				if sub, ok := v.(*Item); ok {
+					visit(sub)
+				}
+			}
+		}
+	}
+	for _, item := range m.Items {
+		visit(item)
+	}
+	return ids
+}
+
+// node returns the JSON-LD node object of the item, contained by an item of
+// the given vocabulary, or a reference to it when it was written before.
+func (j *jsonld) node(item *Item, inherited *Vocabulary) map[string]interface{} {
+	id, shared := j.ids[item]
+	if shared && j.written[item] {
+		return map[string]interface{}{"@id": id}
+	}
+	j.written[item] = true
+
+	vocab := j.r.itemVocabulary(item, inherited)
+	node := make(map[string]interface{})
+	if item.ID != "" {
+		node["@id"] = item.ID
+	} else if shared {
+		node["@id"] = id
+	}
+	if len(item.Types) > 0 {
+		node["@type"] = item.Types
+	}
+	for _, name := range item.PropertyNames() {
+		iri, ok := propertyIRI(firstType(item), name, vocab)
+		if !ok {
+			continue
+		}
+		values, _ := node[iri].([]interface{})
+		for n, v := range item.Values(name) {
+			if sub, ok := v.Value.(*Item); ok {
+				values = append(values, j.node(sub, vocab))
+				continue
+			}
+			if item.valueKind(name, n) == urlValue {
+				values = append(values, map[string]interface{}{"@id": v.Value})
+				continue
+			}
+			if v.Lang != "" {
+				values = append(values, map[string]interface{}{"@value": v.Value, "@language": v.Lang})
+				continue
+			}
+			values = append(values, v.Value)
+		}
+		node[iri] = values
+	}
+	return node
+}
+
+// WriteJSONLD writes the microdata as an indented JSON-LD document to w.
+func (m *Microdata) WriteJSONLD(w io.Writer) error {
+	b, err := json.MarshalIndent(m.JSONLD(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// WriteNTriples writes the microdata as RDF in the N-Triples format to w,
+// expanding property names with the DefaultRegistry, see
+// Registry.WriteNTriples.
+func (m *Microdata) WriteNTriples(w io.Writer) error {
+	return DefaultRegistry.WriteNTriples(w, m)
+}
+
+// WriteNTriples writes the microdata as RDF in the N-Triples format to w,
+// following the Microdata to RDF mapping. Items are identified by their
+// itemid or a blank node, their types are written as rdf:type triples.
+// Property names are expanded to IRIs, see Expand; properties which can't be
+// expanded are left out. URL values are written as IRIs, other values as
+// literals, tagged with their language when known. An item held by more than
+// one item, e.g. through itemref, is written once.
+func (r Registry) WriteNTriples(w io.Writer, m *Microdata) error {
+	bw := bufio.NewWriter(w)
+	blank := 0
+	subjects := make(map[*Item]string)
+	var writeItem func(item *Item, inherited *Vocabulary) string
+	writeItem = func(item *Item, inherited *Vocabulary) string {
+		if subject, ok := subjects[item]; ok {
+			return subject
+		}
+		subject := fmt.Sprintf("_:b%d", blank)
+		blank++
+		if item.ID != "" {
+			subject = ntriplesIRI(item.ID)
+		}
+		subjects[item] = subject
+
+		vocab := r.itemVocabulary(item, inherited)
+		for _, t := range item.Types {
+			fmt.Fprintf(bw, "%s <%s> %s .\n", subject, rdfType, ntriplesIRI(t))
+		}
+		for _, name := range item.PropertyNames() {
+			iri, ok := propertyIRI(firstType(item), name, vocab)
+			if !ok {
+				continue
+			}
+			for n, v := range item.Values(name) {
+				var object string
+				if sub, ok := v.Value.(*Item); ok {
+					object = writeItem(sub, vocab)
+				} else if item.valueKind(name, n) == urlValue {
+					object = ntriplesIRI(fmt.Sprint(v.Value))
+				} else {
+					object = ntriplesLiteral(fmt.Sprint(v.Value))
+					if v.Lang != "" {
+						object += "@" + v.Lang
+					}
+				}
+				fmt.Fprintf(bw, "%s %s %s .\n", subject, ntriplesIRI(iri), object)
+			}
+		}
+		return subject
+	}
+
+	for _, item := range m.Items {
+		writeItem(item, nil)
+	}
+	return bw.Flush()
+}
+
+// ntriplesIRI returns the IRI as an N-Triples IRI reference.
+func ntriplesIRI(iri string) string {
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, r := range iri {
+		switch {
+		case r <= 0x20 || strings.ContainsRune("<>\"{}|^`\\", r):
+			fmt.Fprintf(&b, "\\u%04X", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// ntriplesLiteral returns the text as an N-Triples string literal.
+func ntriplesLiteral(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, "\\u%04X", r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,161 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteNTriples(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://schema.org/Person" itemid="http://example.com/people/penelope">
+			<span itemprop="name">Penelope "Penny"</span>
+			<span itemprop="http://purl.org/dc/terms/title">Dr.</span>
+			<div itemprop="address" itemscope itemtype="http://schema.org/PostalAddress">
+				<span itemprop="addressLocality">Amsterdam</span>
+			</div>
+		</div>
+		<div itemscope>
+			<span itemprop="name">Untyped</span>
+		</div>`
+
+	data := ParseData(html, t)
+
+	var buf bytes.Buffer
+	if err := data.WriteNTriples(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	result := buf.String()
+	expected := `<http://example.com/people/penelope> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://schema.org/Person> .
+<http://example.com/people/penelope> <http://schema.org/name> "Penelope \"Penny\"" .
+<http://example.com/people/penelope> <http://purl.org/dc/terms/title> "Dr." .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://schema.org/PostalAddress> .
+_:b1 <http://schema.org/addressLocality> "Amsterdam" .
+<http://example.com/people/penelope> <http://schema.org/address> _:b1 .
+`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestJSONLD(t *testing.T) {
+	data := ParseData(bookSnippet, t)
+
+	b, err := json.Marshal(data.JSONLD())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := string(b)
+	expected := `{"@graph":[{"@id":"urn:isbn:0-330-34032-8","@type":["http://vocab.example.net/book"],"http://vocab.example.net/author":["Peter F. Hamilton"],"http://vocab.example.net/pubdate":["1996-01-26"],"http://vocab.example.net/title":["The Reality Dysfunction"]}]}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestRDFLanguage(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://schema.org/Product" lang="en">
+			<span itemprop="name">Anvil</span>
+			<span itemprop="name" lang="fr">Enclume</span>
+			<a itemprop="url" href="http://example.com/anvil">Anvil</a>
+		</div>`
+
+	data := ParseData(html, t)
+
+	var buf bytes.Buffer
+	if err := data.WriteNTriples(&buf); err != nil {
+		t.Fatal(err)
+	}
+	result := buf.String()
+	expected := `_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://schema.org/Product> .
+_:b0 <http://schema.org/name> "Anvil"@en .
+_:b0 <http://schema.org/name> "Enclume"@fr .
+_:b0 <http://schema.org/url> <http://example.com/anvil> .
+`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+
+	b, err := json.Marshal(data.JSONLD())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = string(b)
+	expected = `{"@graph":[{"@type":["http://schema.org/Product"],"http://schema.org/name":[{"@language":"en","@value":"Anvil"},{"@language":"fr","@value":"Enclume"}],"http://schema.org/url":[{"@id":"http://example.com/anvil"}]}]}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestRDFURLValues(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://schema.org/Product">
+			<img itemprop="image" src="anvil.png">
+			<time itemprop="releaseDate" datetime="2016-10-11">October 11</time>
+		</div>`
+
+	data := ParseData(html, t)
+
+	var buf bytes.Buffer
+	if err := data.WriteNTriples(&buf); err != nil {
+		t.Fatal(err)
+	}
+	result := buf.String()
+	expected := `_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://schema.org/Product> .
+_:b0 <http://schema.org/image> <http://example.com/anvil.png> .
+_:b0 <http://schema.org/releaseDate> "2016-10-11" .
+`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestNTriplesLiteral(t *testing.T) {
+	result := ntriplesLiteral("a\\b\n\tc")
+	expected := `"a\\b\n\u0009c"`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestRDFSharedItem(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://schema.org/Product" itemref="seller"><span itemprop="name">Anvil</span></div>
+		<div itemscope itemtype="http://schema.org/Product" itemref="seller"><span itemprop="name">Hammer</span></div>
+		<div id="seller" itemprop="seller" itemscope itemtype="http://schema.org/Organization"><span itemprop="name">ACME</span></div>`
+
+	data := ParseData(html, t)
+
+	var buf bytes.Buffer
+	if err := data.WriteNTriples(&buf); err != nil {
+		t.Fatal(err)
+	}
+	result := buf.String()
+	expected := `_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://schema.org/Product> .
+_:b0 <http://schema.org/name> "Anvil" .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://schema.org/Organization> .
+_:b1 <http://schema.org/name> "ACME" .
+_:b0 <http://schema.org/seller> _:b1 .
+_:b2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://schema.org/Product> .
+_:b2 <http://schema.org/name> "Hammer" .
+_:b2 <http://schema.org/seller> _:b1 .
+`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+
+	b, err := json.Marshal(data.JSONLD())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = string(b)
+	expected = `{"@graph":[{"@type":["http://schema.org/Product"],"http://schema.org/name":["Anvil"],"http://schema.org/seller":[{"@id":"_:b0","@type":["http://schema.org/Organization"],"http://schema.org/name":["ACME"]}]},{"@type":["http://schema.org/Product"],"http://schema.org/name":["Hammer"],"http://schema.org/seller":[{"@id":"_:b0"}]}]}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// schemaOrgURI is the canonical schema.org vocabulary URI.
+const schemaOrgURI = "http://schema.org/"
+
+// dataVocabularyURI is the data-vocabulary.org vocabulary URI.
+const dataVocabularyURI = "http://data-vocabulary.org/"
+
+// dataVocabularyTypes maps the data-vocabulary.org types to their schema.org
+// equivalents and the names of their properties which differ.
+var dataVocabularyTypes = map[string]struct {
+	name       string
+	properties map[string]string
+}{
+	"Address": {"PostalAddress", map[string]string{
+		"country-name":   "addressCountry",
+		"locality":       "addressLocality",
+		"postal-code":    "postalCode",
+		"region":         "addressRegion",
+		"street-address": "streetAddress",
+	}},
+	"Event":        {"Event", map[string]string{"photo": "image"}},
+	"Geo":          {"GeoCoordinates", nil},
+	"Offer":        {"Offer", map[string]string{"currency": "priceCurrency"}},
+	"Organization": {"Organization", map[string]string{"tel": "telephone"}},
+	"Person": {"Person", map[string]string{
+		"photo": "image",
+		"role":  "jobTitle",
+		"title": "jobTitle",
+	}},
+	"Product": {"Product", map[string]string{"photo": "image"}},
+	"Rating": {"Rating", map[string]string{
+		"best":  "bestRating",
+		"value": "ratingValue",
+		"worst": "worstRating",
+	}},
+	"Recipe": {"Recipe", map[string]string{
+		"ingredient":   "recipeIngredient",
+		"instructions": "recipeInstructions",
+		"photo":        "image",
+		"published":    "datePublished",
+		"summary":      "description",
+		"yield":        "recipeYield",
+	}},
+	"Review": {"Review", map[string]string{
+		"description":  "reviewBody",
+		"dtreviewed":   "datePublished",
+		"itemreviewed": "itemReviewed",
+		"rating":       "reviewRating",
+		"reviewer":     "author",
+		"summary":      "name",
+	}},
+	"Review-aggregate": {"AggregateRating", map[string]string{
+		"count":        "reviewCount",
+		"itemreviewed": "itemReviewed",
+		"rating":       "ratingValue",
+		"votes":        "ratingCount",
+	}},
+	"Offer-aggregate": {"AggregateOffer", map[string]string{
+		"currency":   "priceCurrency",
+		"highprice":  "highPrice",
+		"lowprice":   "lowPrice",
+		"offercount": "offerCount",
+	}},
+}
+
+// Rename records a type or a property name replaced by NormalizeVocabulary.
+// Path locates the item, e.g. "items[0]" or "items[0].offers[0]".
+type Rename struct {
+	Path     string `json:"path"`
+	Kind     string `json:"kind"` // "type" or "property"
+	Original string `json:"original"`
+	Name     string `json:"name"`
+}
+
+// String returns a description of the rename.
+func (r Rename) String() string {
+	return fmt.Sprintf("%s: %s %q -> %q", r.Path, r.Kind, r.Original, r.Name)
+}
+
+// WithVocabularyNormalization normalizes the types and property names of the
+// items, see Microdata.NormalizeVocabulary. The renames are recorded in the
+// Renames field of the microdata.
+func WithVocabularyNormalization() Option {
+	return func(p *parser) {
+		p.normalizeVocabulary = true
+	}
+}
+
+// NormalizeVocabulary replaces the variants of schema.org types, like
+// https://schema.org/Product, schema.org/Product and
+// http://www.schema.org/Product, by the canonical http://schema.org/Product.
+// The types of the legacy data-vocabulary.org vocabulary are replaced by
+// their schema.org equivalents and the names of their properties which differ
+// are renamed, e.g. the "reviewer" of a http://data-vocabulary.org/Review
+// becomes the "author" of a http://schema.org/Review. It returns the renames
+// of the items and their nested items, in document order.
+func (m *Microdata) NormalizeVocabulary() []Rename {
+	var renames []Rename
+	var normalize func(path string, item *Item)
+	normalize = func(path string, item *Item) {
+		for n, t := range item.Types {
+			name, properties := normalizeType(t)
+			if name != t {
+				item.Types[n] = name
+				renames = append(renames, Rename{Path: path, Kind: "type", Original: t, Name: name})
+			}
+			for _, old := range item.PropertyNames() {
+				if name, ok := properties[old]; ok {
+					item.renameProperty(old, name)
+					renames = append(renames, Rename{Path: path, Kind: "property", Original: old, Name: name})
+				}
+			}
+		}
+
+		for _, name := range item.PropertyNames() {
+			for i, v := range item.Properties[name] {
+				if sub, ok := v.(*Item); ok {
+					normalize(fmt.Sprintf("%s.%s[%d]", path, name, i), sub)
+				}
+			}
+		}
+	}
+
+	for i, item := range m.Items {
+		normalize(fmt.Sprintf("items[%d]", i), item)
+	}
+	return renames
+}
+
+// normalizeType returns the canonical schema.org type of the given type, and
+// the renamed properties of a data-vocabulary.org type. Other types are
+// returned as is.
+func normalizeType(t string) (string, map[string]string) {
+	rest := t
+	for _, prefix := range []string{"http://", "https://", "//"} {
+		if strings.HasPrefix(rest, prefix) {
+			rest = strings.TrimPrefix(rest, prefix)
+			break
+		}
+	}
+	rest = strings.TrimPrefix(rest, "www.")
+
+	switch {
+	case strings.HasPrefix(rest, "schema.org/"):
+		return schemaOrgURI + strings.TrimPrefix(rest, "schema.org/"), nil
+	case strings.HasPrefix(rest, "data-vocabulary.org/"):
+		dv, ok := dataVocabularyTypes[strings.TrimPrefix(rest, "data-vocabulary.org/")]
+		if !ok {
+			return dataVocabularyURI + strings.TrimPrefix(rest, "data-vocabulary.org/"), nil
+		}
+		return schemaOrgURI + dv.name, dv.properties
+	}
+	return t, nil
+}
+
+// renameProperty renames the property of the item. The values are appended
+// to the values of a property with the new name.
+func (i *Item) renameProperty(old, name string) {
+	values, ok := i.Properties[old]
+	if !ok || old == name {
+		return
+	}
+
+	// Keep the details of the values in step with the values.
+	details := i.details[old]
+	if len(details) != len(values) || len(i.details[name]) != len(i.Properties[name]) {
+		delete(i.details, name)
+		details = nil
+	}
+	delete(i.details, old)
+
+	if _, exists := i.Properties[name]; exists {
+		for n, p := range i.order {
+			if p == old {
+				i.order = append(i.order[:n], i.order[n+1:]...)
+				break
+			}
+		}
+	} else {
+		for n, p := range i.order {
+			if p == old {
+				i.order[n] = name
+				break
+			}
+		}
+	}
+
+	i.Properties[name] = append(i.Properties[name], values...)
+	delete(i.Properties, old)
+	if details != nil {
+		i.details[name] = append(i.details[name], details...)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeType(t *testing.T) {
+	var testTable = []struct {
+		itemType string
+		expected string
+	}{
+		{"http://schema.org/Product", "http://schema.org/Product"},
+		{"https://schema.org/Product", "http://schema.org/Product"},
+		{"schema.org/Product", "http://schema.org/Product"},
+		{"//schema.org/Product", "http://schema.org/Product"},
+		{"http://www.schema.org/Product", "http://schema.org/Product"},
+		{"http://data-vocabulary.org/Review-aggregate", "http://schema.org/AggregateRating"},
+		{"data-vocabulary.org/Address", "http://schema.org/PostalAddress"},
+		{"https://data-vocabulary.org/Breadcrumb", "http://data-vocabulary.org/Breadcrumb"},
+		{"http://example.com/schema.org/Product", "http://example.com/schema.org/Product"},
+	}
+
+	for _, test := range testTable {
+		if result, _ := normalizeType(test.itemType); result != test.expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
+		}
+	}
+}
+
+func TestNormalizeVocabulary(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://data-vocabulary.org/Review">
+			<span itemprop="itemreviewed">Anvil</span>
+			<span itemprop="reviewer" lang="en">Wile E.</span>
+			<span itemprop="author">Coyote</span>
+			<div itemprop="rating" itemscope itemtype="http://data-vocabulary.org/Rating">
+				<span itemprop="value">4</span>
+			</div>
+		</div>
+		<div itemscope itemtype="https://schema.org/Product">
+			<span itemprop="name">Anvil</span>
+		</div>`
+
+	u, _ := url.Parse("http://example.com/")
+	data, err := ParseHTML(strings.NewReader(html), "text/html", u, WithVocabularyNormalization())
+	if err != nil {
+		t.Fatal(err)
+	}
+	review, product := data.Items[0], data.Items[1]
+	rating := review.Properties["reviewRating"][0].(*Item)
+
+	var testTable = []struct {
+		result   interface{}
+		expected interface{}
+	}{
+		{review.Types, []string{"http://schema.org/Review"}},
+		{review.PropertyNames(), []string{"itemReviewed", "author", "reviewRating"}},
+		{review.Properties["author"], ValueList{"Coyote", "Wile E."}},
+		{review.Values("author")[1].Lang, "en"},
+		{rating.Types, []string{"http://schema.org/Rating"}},
+		{rating.Properties["ratingValue"], ValueList{"4"}},
+		{product.Types, []string{"http://schema.org/Product"}},
+		{len(data.Renames), 7},
+		{data.Renames[2], Rename{Path: "items[0]", Kind: "property", Original: "reviewer", Name: "author"}},
+		{data.Renames[4].String(), `items[0].reviewRating[0]: type "http://data-vocabulary.org/Rating" -> "http://schema.org/Rating"`},
+	}
+
+	for _, test := range testTable {
+		if !reflect.DeepEqual(test.result, test.expected) {
+			t.Errorf("Result should have been \"%v\", but it was \"%v\"", test.expected, test.result)
+		}
+	}
+}
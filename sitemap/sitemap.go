@@ -0,0 +1,235 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+/*
+
+	Package sitemap reads XML sitemaps and sitemap indexes, as specified by
+	the sitemaps.org protocol, to find the pages to extract microdata from.
+
+	Usage:
+
+	Read a sitemap, or all the sitemaps of a sitemap index, from an URL or a
+	file. Gzipped sitemaps are decompressed.
+		urls, err := sitemap.Read("http://example.com/sitemap.xml")
+
+	Keep only the pages modified since the last run.
+		urls = sitemap.Since(urls, lastRun)
+*/
+package sitemap
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSize is the maximum size of an uncompressed sitemap.
+const maxSize = 50 << 20
+
+// URL is an entry of a sitemap or a sitemap index. LastMod is the zero time
+// when the entry has no valid lastmod.
+type URL struct {
+	Loc        string
+	LastMod    time.Time
+	ChangeFreq string
+	Priority   float64
+}
+
+// entry is the XML representation of a URL.
+type entry struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
+
+// url returns the URL of the entry.
+func (e entry) url() URL {
+	u := URL{
+		Loc:        strings.TrimSpace(e.Loc),
+		LastMod:    parseTime(e.LastMod),
+		ChangeFreq: strings.TrimSpace(e.ChangeFreq),
+	}
+	if p, err := strconv.ParseFloat(strings.TrimSpace(e.Priority), 64); err == nil {
+		u.Priority = p
+	}
+	return u
+}
+
+// layouts are the W3C Datetime formats of lastmod.
+var layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// parseTime returns the time of the W3C Datetime value, or the zero time.
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Parse parses the sitemap or sitemap index in r, which may be gzipped. It
+// returns the page URLs of a sitemap and the sitemap URLs of a sitemap index.
+func Parse(r io.Reader) (urls, sitemaps []URL, err error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer zr.Close()
+		r = zr
+	} else {
+		r = br
+	}
+
+	var doc struct {
+		URLs     []entry `xml:"url"`
+		Sitemaps []entry `xml:"sitemap"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(r, maxSize)).Decode(&doc); err != nil {
+		return nil, nil, err
+	}
+
+	for _, e := range doc.URLs {
+		urls = append(urls, e.url())
+	}
+	for _, e := range doc.Sitemaps {
+		sitemaps = append(sitemaps, e.url())
+	}
+	return urls, sitemaps, nil
+}
+
+// Since returns the URLs modified after the given time. URLs without a
+// lastmod are always returned.
+func Since(urls []URL, t time.Time) []URL {
+	var modified []URL
+	for _, u := range urls {
+		if u.LastMod.IsZero() || u.LastMod.After(t) {
+			modified = append(modified, u)
+		}
+	}
+	return modified
+}
+
+// Reader reads sitemaps from URLs and files. The zero value is ready to use.
+type Reader struct {
+	// Client is the HTTP client used for requests. The default is
+	// http.DefaultClient.
+	Client *http.Client
+
+	// UserAgent, when set, is sent with every request.
+	UserAgent string
+
+	// Since, when set, skips the sitemaps of a sitemap index and the pages
+	// with a lastmod before it.
+	Since time.Time
+}
+
+// Read returns the page URLs of the sitemap at the given URL or file path.
+// The sitemaps of a sitemap index are read in turn.
+func Read(src string) ([]URL, error) {
+	return (&Reader{}).Read(context.Background(), src)
+}
+
+// Read returns the page URLs of the sitemap at the given URL or file path.
+// The sitemaps of a sitemap index are read in turn, a nested sitemap index is
+// an error. Only src may be a file path; the locs of the sitemap index, and of
+// the fetched sitemaps, must be absolute http or https URLs.
+func (r *Reader) Read(ctx context.Context, src string) ([]URL, error) {
+	urls, sitemaps, err := r.read(ctx, src, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sm := range r.filter(sitemaps) {
+		u, nested, err := r.read(ctx, sm.Loc, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(nested) > 0 {
+			return nil, fmt.Errorf("sitemap: %s: nested sitemap index", sm.Loc)
+		}
+		urls = append(urls, u...)
+	}
+	return r.filter(urls), nil
+}
+
+// filter returns the URLs modified after r.Since.
+func (r *Reader) filter(urls []URL) []URL {
+	if r.Since.IsZero() {
+		return urls
+	}
+	return Since(urls, r.Since)
+}
+
+// read parses the sitemap at the given URL, or file path when top is set.
+func (r *Reader) read(ctx context.Context, src string, top bool) ([]URL, []URL, error) {
+	if !isHTTP(src) {
+		if !top {
+			return nil, nil, fmt.Errorf("sitemap: %q is not an absolute http or https URL", src)
+		}
+		f, err := os.Open(strings.TrimPrefix(src, "file://"))
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close()
+		return Parse(f)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, src, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req = req.WithContext(ctx)
+	if r.UserAgent != "" {
+		req.Header.Set("User-Agent", r.UserAgent)
+	}
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("sitemap: fetching %s: %s", src, resp.Status)
+	}
+
+	urls, sitemaps, err := Parse(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, u := range append(urls[:len(urls):len(urls)], sitemaps...) {
+		if !isHTTP(u.Loc) {
+			return nil, nil, fmt.Errorf("sitemap: %s: loc %q is not an absolute http or https URL", src, u.Loc)
+		}
+	}
+	return urls, sitemaps, nil
+}
+
+// isHTTP reports whether s is an absolute http or https URL.
+func isHTTP(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
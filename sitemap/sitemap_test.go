@@ -0,0 +1,178 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var urlset = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>http://www.example.com/</loc>
+    <lastmod>2005-01-01</lastmod>
+    <changefreq>monthly</changefreq>
+    <priority>0.8</priority>
+  </url>
+  <url>
+    <loc> http://www.example.com/catalog?item=12&amp;desc=vacation_hawaii </loc>
+    <lastmod>2004-12-23T18:00:15+00:00</lastmod>
+  </url>
+  <url>
+    <loc>http://www.example.com/catalog?item=73&amp;desc=vacation_new_zealand</loc>
+  </url>
+</urlset>`
+
+func TestParse(t *testing.T) {
+	urls, sitemaps, err := Parse(strings.NewReader(urlset))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sitemaps) != 0 || len(urls) != 3 {
+		t.Fatalf("Result should have been 3 URLs, but it was %v and %v", urls, sitemaps)
+	}
+
+	var testTable = []struct {
+		result   interface{}
+		expected interface{}
+	}{
+		{urls[0].Loc, "http://www.example.com/"},
+		{urls[0].LastMod, time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{urls[0].ChangeFreq, "monthly"},
+		{urls[0].Priority, 0.8},
+		{urls[1].Loc, "http://www.example.com/catalog?item=12&desc=vacation_hawaii"},
+		{urls[1].LastMod.Equal(time.Date(2004, 12, 23, 18, 0, 15, 0, time.UTC)), true},
+		{urls[2].LastMod.IsZero(), true},
+	}
+	for _, test := range testTable {
+		if test.result != test.expected {
+			t.Errorf("Result should have been \"%v\", but it was \"%v\"", test.expected, test.result)
+		}
+	}
+}
+
+func TestParseGzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(urlset))
+	zw.Close()
+
+	urls, _, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 3 {
+		t.Errorf("Result should have been 3 URLs, but it was %d", len(urls))
+	}
+}
+
+func TestSince(t *testing.T) {
+	urls, _, _ := Parse(strings.NewReader(urlset))
+
+	result := Since(urls, time.Date(2004, 12, 31, 0, 0, 0, 0, time.UTC))
+	if len(result) != 2 || result[0].Loc != urls[0].Loc || result[1].Loc != urls[2].Loc {
+		t.Errorf("Result should have been the first and the last URL, but it was %v", result)
+	}
+}
+
+func TestReadIndex(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap_index.xml":
+			fmt.Fprintf(w, `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/sitemap1.xml.gz</loc><lastmod>2005-01-01</lastmod></sitemap>
+  <sitemap><loc>%[1]s/sitemap2.xml</loc><lastmod>2004-01-01</lastmod></sitemap>
+</sitemapindex>`, ts.URL)
+		case "/sitemap1.xml.gz":
+			w.Header().Set("Content-Type", "application/gzip")
+			zw := gzip.NewWriter(w)
+			zw.Write([]byte(urlset))
+			zw.Close()
+		case "/sitemap2.xml":
+			fmt.Fprint(w, `<urlset><url><loc>http://www.example.com/old</loc></url></urlset>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	urls, err := Read(ts.URL + "/sitemap_index.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 4 {
+		t.Errorf("Result should have been 4 URLs, but it was %d", len(urls))
+	}
+
+	r := &Reader{Since: time.Date(2004, 12, 31, 0, 0, 0, 0, time.UTC)}
+	urls, err = r.Read(context.Background(), ts.URL+"/sitemap_index.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 2 {
+		t.Errorf("Result should have been 2 URLs, but it was %v", urls)
+	}
+
+	if _, err := Read(ts.URL + "/missing.xml"); err == nil {
+		t.Error("Result should have been an error, but it was nil")
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sitemap.xml")
+	if err := os.WriteFile(path, []byte(urlset), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, src := range []string{path, "file://" + path} {
+		urls, err := Read(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(urls) != 3 {
+			t.Errorf("%s: Result should have been 3 URLs, but it was %d", src, len(urls))
+		}
+	}
+}
+
+func TestReadLocs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sitemap.xml")
+	if err := os.WriteFile(path, []byte(urlset), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/file_index.xml":
+			fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%s</loc></sitemap></sitemapindex>`, path)
+		case "/file_url_index.xml":
+			fmt.Fprintf(w, `<sitemapindex><sitemap><loc>file://%s</loc></sitemap></sitemapindex>`, path)
+		case "/relative.xml":
+			fmt.Fprint(w, `<urlset><url><loc>/page</loc></url></urlset>`)
+		case "/ftp.xml":
+			fmt.Fprint(w, `<urlset><url><loc>ftp://example.com/page</loc></url></urlset>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	for _, p := range []string{"/file_index.xml", "/file_url_index.xml", "/relative.xml", "/ftp.xml"} {
+		urls, err := Read(ts.URL + p)
+		if err == nil || !strings.Contains(err.Error(), "not an absolute http or https URL") {
+			t.Errorf("%s: Result should have been an error, but it was %v, %v", p, urls, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Profile maps item types to the names of their required properties.
+type Profile map[string][]string
+
+// SchemaOrg is a validation profile with the properties search engines
+// require for common schema.org types.
+var SchemaOrg = Profile{
+	"http://schema.org/AggregateRating": {"ratingValue"},
+	"http://schema.org/Article":         {"headline"},
+	"http://schema.org/BlogPosting":     {"headline"},
+	"http://schema.org/BreadcrumbList":  {"itemListElement"},
+	"http://schema.org/Event":           {"name", "startDate", "location"},
+	"http://schema.org/ListItem":        {"position"},
+	"http://schema.org/NewsArticle":     {"headline"},
+	"http://schema.org/Offer":           {"price", "priceCurrency"},
+	"http://schema.org/Organization":    {"name"},
+	"http://schema.org/Person":          {"name"},
+	"http://schema.org/Product":         {"name"},
+	"http://schema.org/Rating":          {"ratingValue"},
+	"http://schema.org/Recipe":          {"name"},
+	"http://schema.org/Review":          {"author", "itemReviewed"},
+}
+
+// Profiles holds the validation profiles by name.
+var Profiles = map[string]Profile{
+	"schema.org": SchemaOrg,
+}
+
+// Violation describes a required property missing on an item. Path locates the
+// item, e.g. "items[0]" or "items[0].offers[0]".
+type Violation struct {
+	Path     string `json:"path"`
+	Type     string `json:"type"`
+	Property string `json:"property"`
+}
+
+// Error returns a description of the violation.
+func (v Violation) Error() string {
+	return fmt.Sprintf("%s: %s is missing required property %q", v.Path, v.Type, v.Property)
+}
+
+// required returns the required properties for the given type. The https
+// variant of a schema.org type shares the properties of the http variant.
+func (p Profile) required(t string) []string {
+	if props, ok := p[t]; ok {
+		return props
+	}
+	if strings.HasPrefix(t, "https://schema.org/") {
+		return p["http://"+strings.TrimPrefix(t, "https://")]
+	}
+	return nil
+}
+
+// Validate returns the violations of the profile by the items and their
+// nested items, in document order.
+func (p Profile) Validate(data *Microdata) []Violation {
+	var violations []Violation
+	var validate func(path string, item *Item)
+	validate = func(path string, item *Item) {
+		for _, t := range item.Types {
+			for _, prop := range p.required(t) {
+				if len(item.Properties[prop]) == 0 {
+					violations = append(violations, Violation{Path: path, Type: t, Property: prop})
+				}
+			}
+		}
+		for _, name := range item.PropertyNames() {
+			for i, v := range item.Properties[name] {
+				if sub, ok := v.(*Item); ok {
+					validate(fmt.Sprintf("%s.%s[%d]", path, name, i), sub)
+				}
+			}
+		}
+	}
+
+	for i, item := range data.Items {
+		validate(fmt.Sprintf("items[%d]", i), item)
+	}
+	return violations
+}
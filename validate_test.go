@@ -0,0 +1,36 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	html := `
+		<div itemscope itemtype="https://schema.org/Product">
+			<span itemprop="name">Kettle</span>
+			<div itemprop="offers" itemscope itemtype="http://schema.org/Offer">
+				<span itemprop="price">19.95</span>
+			</div>
+		</div>
+		<div itemscope itemtype="http://schema.org/Person"></div>`
+
+	data := ParseData(html, t)
+
+	var testTable = []string{
+		`items[0].offers[0]: http://schema.org/Offer is missing required property "priceCurrency"`,
+		`items[1]: http://schema.org/Person is missing required property "name"`,
+	}
+
+	violations := SchemaOrg.Validate(data)
+	if len(violations) != len(testTable) {
+		t.Fatalf("Result should have been %d violations, but it was %d: %v", len(testTable), len(violations), violations)
+	}
+	for i, expected := range testTable {
+		if result := violations[i].Error(); result != expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+		}
+	}
+}
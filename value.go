@@ -0,0 +1,67 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+// Value is a property value of an item with the details which the properties
+// map doesn't hold.
+type Value struct {
+	// Value is the string or the *Item of the property.
+	Value interface{}
+
+	// Lang is the language of a text value: the lang attribute of its element
+	// or its nearest ancestor, or the default language of the document. It's
+	// empty when the language is unknown, and for URLs, machine-readable
+	// values and items.
+	Lang string
+
+	// HTML is the inner HTML of the element of a text value, when captured
+	// with the WithHTML option. It's empty for values of content attributes.
+	HTML string
+}
+
+// valueDetails holds the details of a property value.
+type valueDetails struct {
+	kind valueKind
+	lang string
+	html string
+}
+
+// addDetails adds the details of the last value of the property.
+func (i *Item) addDetails(property string, details valueDetails) {
+	if i.details == nil {
+		i.details = make(map[string][]valueDetails)
+	}
+	i.details[property] = append(i.details[property], details)
+}
+
+// Values returns the values of the property with their details, in the order
+// of the properties map. Values of items which weren't parsed, or whose
+// properties were modified, have no details.
+func (i *Item) Values(property string) []Value {
+	list := i.Properties[property]
+	details := i.details[property]
+	if len(details) != len(list) {
+		details = nil
+	}
+
+	values := make([]Value, len(list))
+	for n, v := range list {
+		values[n].Value = v
+		if details != nil {
+			values[n].Lang = details[n].lang
+			values[n].HTML = details[n].html
+		}
+	}
+	return values
+}
+
+// valueKind returns the kind of the nth value of the property, or
+// unknownValue when the values have no details.
+func (i *Item) valueKind(property string, n int) valueKind {
+	details := i.details[property]
+	if len(details) != len(i.Properties[property]) {
+		return unknownValue
+	}
+	return details[n].kind
+}
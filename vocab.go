@@ -0,0 +1,177 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Vocabulary describes how the property names of the items of a vocabulary
+// expand to IRIs.
+type Vocabulary struct {
+	// URI is the vocabulary URI. Item types starting with it belong to the
+	// vocabulary.
+	URI string
+
+	// PropertyURI is the prefix of the IRIs of the property names. The
+	// default is URI.
+	PropertyURI string
+
+	// Properties maps property names to IRIs, overriding PropertyURI.
+	Properties map[string]string
+
+	// typeProperties, when set, returns the names of the properties of the
+	// given item type which differ from their names in PropertyURI.
+	typeProperties func(itemType string) map[string]string
+}
+
+// PropertyIRI returns the IRI of the property with the given name. Absolute
+// URLs are returned as is.
+func (v *Vocabulary) PropertyIRI(name string) string {
+	return v.TypePropertyIRI("", name)
+}
+
+// TypePropertyIRI returns the IRI of the property with the given name of an
+// item of the given type, which may be empty for untyped items. Absolute URLs
+// are returned as is.
+func (v *Vocabulary) TypePropertyIRI(itemType, name string) string {
+	if isAbsoluteURL(name) {
+		return name
+	}
+	if iri, ok := v.Properties[name]; ok {
+		return iri
+	}
+	if v.typeProperties != nil && itemType != "" {
+		if renamed, ok := v.typeProperties(itemType)[name]; ok {
+			name = renamed
+		}
+	}
+	if v.PropertyURI != "" {
+		return v.PropertyURI + name
+	}
+	return v.URI + name
+}
+
+// SchemaOrgVocabulary is the schema.org vocabulary.
+var SchemaOrgVocabulary = &Vocabulary{URI: "http://schema.org/"}
+
+// DataVocabulary is the legacy data-vocabulary.org vocabulary. The property
+// names of its types expand to the equivalent schema.org properties, e.g. the
+// "reviewer" of a http://data-vocabulary.org/Review expands to
+// http://schema.org/author, like NormalizeVocabulary renames them.
+var DataVocabulary = &Vocabulary{
+	URI:            dataVocabularyURI,
+	PropertyURI:    schemaOrgURI,
+	typeProperties: dataVocabularyProperties,
+}
+
+// dataVocabularyProperties returns the schema.org names of the properties of
+// the data-vocabulary.org type which differ.
+func dataVocabularyProperties(itemType string) map[string]string {
+	_, properties := normalizeType(itemType)
+	return properties
+}
+
+// Registry is a list of vocabularies, used to expand the property names of
+// items to IRIs.
+type Registry []*Vocabulary
+
+// DefaultRegistry is the registry used by the RDF and JSON-LD exporters of
+// Microdata.
+var DefaultRegistry = Registry{
+	SchemaOrgVocabulary,
+	{URI: "https://schema.org/"},
+	DataVocabulary,
+}
+
+// Vocabulary returns the vocabulary of the given item type: the vocabulary of
+// the registry with the longest URI the type starts with or, when there's no
+// such vocabulary, the type up to and including its last "#" or "/". It
+// returns nil when the type has neither.
+func (r Registry) Vocabulary(itemType string) *Vocabulary {
+	var found *Vocabulary
+	for _, v := range r {
+		if strings.HasPrefix(itemType, v.URI) && (found == nil || len(v.URI) > len(found.URI)) {
+			found = v
+		}
+	}
+	if found != nil {
+		return found
+	}
+
+	if i := strings.LastIndexAny(itemType, "#/"); i >= 0 {
+		return &Vocabulary{URI: itemType[:i+1]}
+	}
+	return nil
+}
+
+// itemVocabulary returns the vocabulary of the item's first type, or the
+// given vocabulary of the containing item when the item has no type.
+func (r Registry) itemVocabulary(item *Item, inherited *Vocabulary) *Vocabulary {
+	if len(item.Types) == 0 {
+		return inherited
+	}
+	return r.Vocabulary(item.Types[0])
+}
+
+// firstType returns the first type of the item, or "" when it has no type.
+func firstType(item *Item) string {
+	if len(item.Types) == 0 {
+		return ""
+	}
+	return item.Types[0]
+}
+
+// Expand returns the IRIs of the property names of the items and their
+// nested items, by item and property name. Property names which are absolute
+// URLs are used as is, other names are expanded in the vocabulary of the
+// item's first type, see Vocabulary.TypePropertyIRI. Items without a type use
+// the vocabulary of the item containing them. Names which can't be expanded
+// are left out.
+func (r Registry) Expand(data *Microdata) map[*Item]map[string]string {
+	iris := make(map[*Item]map[string]string)
+	var expand func(item *Item, inherited *Vocabulary)
+	expand = func(item *Item, inherited *Vocabulary) {
+		if _, ok := iris[item]; ok {
+			return
+		}
+		vocab := r.itemVocabulary(item, inherited)
+		names := make(map[string]string)
+		iris[item] = names
+		for name, values := range item.Properties {
+			if iri, ok := propertyIRI(firstType(item), name, vocab); ok {
+				names[name] = iri
+			}
+			for _, v := range values {
+				if sub, ok := v.(*Item); ok {
+					expand(sub, vocab)
+				}
+			}
+		}
+	}
+	for _, item := range data.Items {
+		expand(item, nil)
+	}
+	return iris
+}
+
+// propertyIRI returns the IRI of the property name of an item of the given
+// type in the vocabulary, which may be nil. It returns false when the name
+// can't be expanded.
+func propertyIRI(itemType, name string, vocab *Vocabulary) (string, bool) {
+	if isAbsoluteURL(name) {
+		return name, true
+	}
+	if vocab == nil {
+		return "", false
+	}
+	return vocab.TypePropertyIRI(itemType, name), true
+}
+
+// isAbsoluteURL reports whether the string is an absolute URL.
+func isAbsoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.IsAbs()
+}
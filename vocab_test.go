@@ -0,0 +1,118 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRegistryVocabulary(t *testing.T) {
+	var testTable = []struct {
+		itemType string
+		expected string
+	}{
+		{"http://schema.org/Person", "http://schema.org/"},
+		{"https://schema.org/Person", "https://schema.org/"},
+		{"http://data-vocabulary.org/Review", "http://data-vocabulary.org/"},
+		{"http://example.com/vocab#Thing", "http://example.com/vocab#"},
+		{"http://example.com/vocab/Thing", "http://example.com/vocab/"},
+	}
+
+	for _, test := range testTable {
+		v := DefaultRegistry.Vocabulary(test.itemType)
+		if v == nil || v.URI != test.expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%v\"", test.expected, v)
+		}
+	}
+
+	if v := DefaultRegistry.Vocabulary("Thing"); v != nil {
+		t.Errorf("Result should have been nil, but it was \"%v\"", v)
+	}
+}
+
+func TestRegistryExpand(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://schema.org/Person">
+			<span itemprop="name">Penelope</span>
+			<span itemprop="http://purl.org/dc/terms/title">Dr.</span>
+			<div itemprop="address" itemscope>
+				<span itemprop="addressLocality">Amsterdam</span>
+			</div>
+		</div>
+		<div itemscope itemtype="http://data-vocabulary.org/Review">
+			<span itemprop="itemreviewed">Anvil</span>
+			<span itemprop="reviewer">Wile E.</span>
+			<span itemprop="summary">Heavy</span>
+		</div>
+		<div itemscope itemtype="http://data-vocabulary.org/Recipe">
+			<span itemprop="summary">Quick</span>
+			<img itemprop="photo" src="pie.jpg">
+			<span itemprop="title">Pie</span>
+		</div>
+		<div itemscope>
+			<span itemprop="name">Untyped</span>
+		</div>`
+
+	data := ParseData(html, t)
+	iris := DefaultRegistry.Expand(data)
+	person, review, recipe, untyped := data.Items[0], data.Items[1], data.Items[2], data.Items[3]
+	address := person.Properties["address"][0].(*Item)
+
+	var testTable = []struct {
+		item     *Item
+		name     string
+		expected string
+	}{
+		{person, "name", "http://schema.org/name"},
+		{person, "http://purl.org/dc/terms/title", "http://purl.org/dc/terms/title"},
+		{person, "address", "http://schema.org/address"},
+		{address, "addressLocality", "http://schema.org/addressLocality"},
+		{review, "itemreviewed", "http://schema.org/itemReviewed"},
+		{review, "reviewer", "http://schema.org/author"},
+		{review, "summary", "http://schema.org/name"},
+		{recipe, "summary", "http://schema.org/description"},
+		{recipe, "photo", "http://schema.org/image"},
+		{recipe, "title", "http://schema.org/title"},
+		{untyped, "name", ""},
+	}
+
+	for _, test := range testTable {
+		if result := iris[test.item][test.name]; result != test.expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
+		}
+	}
+
+	registry := Registry{{URI: "http://schema.org/", PropertyURI: "http://example.com/terms#"}}
+	if result := registry.Expand(data)[address]["addressLocality"]; result != "http://example.com/terms#addressLocality" {
+		t.Errorf("Result should have been \"http://example.com/terms#addressLocality\", but it was \"%s\"", result)
+	}
+}
+
+func TestRegistryWriteNTriples(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://data-vocabulary.org/Person">
+			<span itemprop="title">Engineer</span>
+			<div itemprop="address" itemscope itemtype="http://data-vocabulary.org/Address">
+				<span itemprop="locality">Amsterdam</span>
+			</div>
+		</div>`
+
+	data := ParseData(html, t)
+
+	var buf bytes.Buffer
+	if err := data.WriteNTriples(&buf); err != nil {
+		t.Fatal(err)
+	}
+	result := buf.String()
+	expected := `_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://data-vocabulary.org/Person> .
+_:b0 <http://schema.org/jobTitle> "Engineer" .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://data-vocabulary.org/Address> .
+_:b1 <http://schema.org/addressLocality> "Amsterdam" .
+_:b0 <http://schema.org/address> _:b1 .
+`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
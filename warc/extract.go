@@ -0,0 +1,132 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package warc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+
+	"github.com/namsral/microdata"
+)
+
+// Result is the microdata of an archived response. Data is nil when Err is
+// set.
+type Result struct {
+	// RecordID is the WARC-Record-ID of the record.
+	RecordID string
+
+	// URI is the target URI of the record, used as the base URL.
+	URI string
+
+	// Status is the HTTP status code of a response record, or 0 for a
+	// resource record.
+	Status int
+
+	Data *microdata.Microdata
+	Err  error
+}
+
+// Extractor extracts the microdata of the HTML responses and resources in a
+// WARC file.
+type Extractor struct {
+	r      *Reader
+	result *Result
+}
+
+// NewExtractor returns an extractor of the WARC file in r, see NewReader.
+func NewExtractor(r io.Reader) (*Extractor, error) {
+	wr, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Extractor{r: wr}, nil
+}
+
+// Next advances to the next HTML response or resource record and extracts its
+// microdata, which is then available through Result. Other records are
+// skipped. It returns false at the end of the file or on an error reading the
+// file; errors of a single record are reported by its result.
+func (e *Extractor) Next() bool {
+	for e.r.Next() {
+		record := e.r.Record()
+		if result, ok := extract(record); ok {
+			e.result = result
+			return true
+		}
+	}
+	return false
+}
+
+// Result returns the current result.
+func (e *Extractor) Result() *Result {
+	return e.result
+}
+
+// Err returns the first error reading the file, or nil at the end of the file.
+func (e *Extractor) Err() error {
+	return e.r.Err()
+}
+
+// extract returns the result of the record. It returns false when the record
+// isn't an HTML response or resource.
+func extract(record *Record) (*Result, bool) {
+	result := &Result{
+		RecordID: record.Header.Get("WARC-Record-ID"),
+		URI:      record.TargetURI(),
+	}
+
+	var body io.Reader
+	var contentType string
+	switch record.Type() {
+	case "response":
+		if mediaType, params, _ := mime.ParseMediaType(record.Header.Get("Content-Type")); mediaType != "application/http" || params["msgtype"] == "request" {
+			return nil, false
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(record.Body), nil)
+		if err != nil {
+			result.Err = err
+			return result, true
+		}
+		defer resp.Body.Close()
+		result.Status = resp.StatusCode
+		contentType, body = resp.Header.Get("Content-Type"), resp.Body
+		if resp.Header.Get("Content-Encoding") == "gzip" && isHTML(contentType) {
+			zr, err := gzip.NewReader(resp.Body)
+			if err != nil {
+				result.Err = err
+				return result, true
+			}
+			body = zr
+		}
+	case "resource":
+		contentType, body = record.Header.Get("Content-Type"), record.Body
+	default:
+		return nil, false
+	}
+
+	if !isHTML(contentType) {
+		return nil, false
+	}
+
+	u, err := url.Parse(result.URI)
+	if err != nil {
+		result.Err = err
+		return result, true
+	}
+	result.Data, result.Err = microdata.ParseHTML(body, contentType, u)
+	if result.Err != nil {
+		result.Data = nil
+	}
+	return result, true
+}
+
+// isHTML reports whether the content type is an HTML media type.
+func isHTML(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
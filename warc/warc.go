@@ -0,0 +1,150 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+/*
+
+	Package warc reads WARC archives and extracts the HTML microdata of the
+	archived responses.
+
+	Usage:
+
+	Iterate the HTML responses of a WARC file, gzipped or not.
+		e, err := warc.NewExtractor(f)
+		for e.Next() {
+			result := e.Result()
+			items := result.Data.Items
+		}
+		if err := e.Err(); err != nil {
+			...
+		}
+
+	Iterate the raw records.
+		r, err := warc.NewReader(f)
+		for r.Next() {
+			record := r.Record()
+		}
+*/
+package warc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// Record is a WARC record. Body is valid until the next call to Next of the
+// reader.
+type Record struct {
+	Header textproto.MIMEHeader
+	Body   io.Reader
+}
+
+// Type returns the WARC-Type of the record, e.g. "response".
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// TargetURI returns the WARC-Target-URI of the record. Angle brackets, used
+// by some WARC 1.0 writers, are removed.
+func (r *Record) TargetURI() string {
+	return strings.TrimSuffix(strings.TrimPrefix(r.Header.Get("WARC-Target-URI"), "<"), ">")
+}
+
+// Reader reads the records of a WARC file.
+type Reader struct {
+	br     *bufio.Reader
+	tp     *textproto.Reader
+	record *Record
+	body   *io.LimitedReader
+	err    error
+}
+
+// NewReader returns a reader of the WARC file in r. Gzipped files, including
+// files with a gzip member per record, are decompressed.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(zr)
+	}
+	return &Reader{br: br, tp: textproto.NewReader(br)}, nil
+}
+
+// Next advances to the next record, which is then available through Record.
+// It returns false at the end of the file or on an error.
+func (r *Reader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	if r.body != nil {
+		// Skip the rest of the previous block and the two CRLFs ending the
+		// record.
+		if _, err := io.Copy(io.Discard, r.body); err != nil {
+			r.err = err
+			return false
+		}
+		if r.body.N > 0 {
+			r.err = io.ErrUnexpectedEOF
+			return false
+		}
+		r.body = nil
+	}
+
+	// Skip the blank lines between records.
+	var version string
+	for {
+		line, err := r.tp.ReadLine()
+		if err == io.EOF {
+			return false
+		}
+		if err != nil {
+			r.err = err
+			return false
+		}
+		if line != "" {
+			version = line
+			break
+		}
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		r.err = fmt.Errorf("warc: invalid record version %q", version)
+		return false
+	}
+
+	header, err := r.tp.ReadMIMEHeader()
+	if err != nil {
+		r.err = err
+		return false
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		r.err = errors.New("warc: invalid Content-Length")
+		return false
+	}
+
+	r.body = &io.LimitedReader{R: r.br, N: length}
+	r.record = &Record{Header: header, Body: r.body}
+	return true
+}
+
+// Record returns the current record.
+func (r *Reader) Record() *Record {
+	return r.record
+}
+
+// Err returns the first error of the reader, or nil at the end of the file.
+func (r *Reader) Err() error {
+	if r.err == io.ErrUnexpectedEOF {
+		return errors.New("warc: unexpected end of file")
+	}
+	return r.err
+}
@@ -0,0 +1,119 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// record returns a WARC record with the given type, target URI, content type
+// and block.
+func record(typ, uri, contentType, block string) string {
+	return fmt.Sprintf("WARC/1.0\r\nWARC-Type: %s\r\nWARC-Record-ID: <urn:uuid:%d>\r\nWARC-Target-URI: %s\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s\r\n\r\n",
+		typ, len(block), uri, contentType, len(block), block)
+}
+
+// response returns an HTTP response with the given content type and body.
+func response(contentType, body string) string {
+	return fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s", contentType, len(body), body)
+}
+
+var archive = []string{
+	record("warcinfo", "", "application/warc-fields", "software: test\r\n"),
+	record("request", "http://example.com/product", "application/http; msgtype=request", "GET /product HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+	record("response", "http://example.com/product", "application/http; msgtype=response", response("text/html; charset=utf-8",
+		`<div itemscope itemtype="http://schema.org/Product"><a itemprop="url" href="kettle">Kettle</a></div>`)),
+	record("response", "<http://example.com/logo.png>", "application/http; msgtype=response", response("image/png", "\x89PNG")),
+	record("resource", "http://example.com/saved.html", "text/html", `<div itemscope itemtype="http://schema.org/Person"><span itemprop="name">Penelope</span></div>`),
+}
+
+func TestExtractor(t *testing.T) {
+	var gz bytes.Buffer
+	for _, r := range archive {
+		// Write a gzip member per record, like most WARC writers.
+		zw := gzip.NewWriter(&gz)
+		io.WriteString(zw, r)
+		zw.Close()
+	}
+
+	for name, src := range map[string]io.Reader{
+		"warc":    strings.NewReader(strings.Join(archive, "")),
+		"warc.gz": &gz,
+	} {
+		e, err := NewExtractor(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var results []*Result
+		for e.Next() {
+			results = append(results, e.Result())
+		}
+		if err := e.Err(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("%s: Result should have been 2 results, but it was %d", name, len(results))
+		}
+
+		var testTable = []struct {
+			result   interface{}
+			expected interface{}
+		}{
+			{results[0].URI, "http://example.com/product"},
+			{results[0].Status, 200},
+			{results[0].Data.Items[0].Properties["url"][0], "http://example.com/kettle"},
+			{results[1].URI, "http://example.com/saved.html"},
+			{results[1].Data.Items[0].Properties["name"][0], "Penelope"},
+		}
+		for _, test := range testTable {
+			if test.result != test.expected {
+				t.Errorf("%s: Result should have been \"%v\", but it was \"%v\"", name, test.expected, test.result)
+			}
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	r, err := NewReader(strings.NewReader(strings.Join(archive, "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	for r.Next() {
+		types = append(types, r.Record().Type())
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	result := strings.Join(types, " ")
+	expected := "warcinfo request response response resource"
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+
+	if uri := r.Record().TargetURI(); uri != "http://example.com/saved.html" {
+		t.Errorf("Result should have been \"http://example.com/saved.html\", but it was \"%s\"", uri)
+	}
+}
+
+func TestReaderTruncated(t *testing.T) {
+	src := strings.Join(archive, "")
+	r, err := NewReader(strings.NewReader(src[:len(src)-40]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for r.Next() {
+	}
+	if r.Err() == nil {
+		t.Error("Result should have been an error, but it was nil")
+	}
+}
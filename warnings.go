@@ -0,0 +1,142 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// WarningCode identifies a kind of markup anomaly.
+type WarningCode string
+
+// The warning codes.
+const (
+	// InvalidItemID is an itemid attribute which isn't a valid URL. The
+	// itemid is ignored.
+	InvalidItemID WarningCode = "invalid-itemid"
+
+	// ItemIDWithoutType is an itemid attribute on an item without an
+	// itemtype attribute. The itemid is ignored.
+	ItemIDWithoutType WarningCode = "itemid-without-itemtype"
+
+	// MissingItemRef is an itemref attribute referencing an ID which isn't
+	// in the document.
+	MissingItemRef WarningCode = "missing-itemref"
+
+	// EmptyItemProp is an itemprop attribute without property names. The
+	// element is ignored.
+	EmptyItemProp WarningCode = "empty-itemprop"
+
+	// ItemPropOutsideItem is an element with an itemprop attribute which
+	// isn't part of an item. The property, or the item it holds, is ignored.
+	ItemPropOutsideItem WarningCode = "itemprop-outside-item"
+)
+
+// Warning describes a markup anomaly found while parsing. Path locates the
+// element, e.g. "/html/body/div[2]/span".
+type Warning struct {
+	Code    WarningCode `json:"code"`
+	Message string      `json:"message"`
+	Path    string      `json:"path"`
+}
+
+// Error returns a description of the warning.
+func (w Warning) Error() string {
+	return fmt.Sprintf("%s: %s", w.Path, w.Message)
+}
+
+// StrictError is the error returned when parsing in strict mode finds
+// markup anomalies.
+type StrictError struct {
+	Warnings []Warning
+}
+
+// Error returns a description of the warnings.
+func (e *StrictError) Error() string {
+	msgs := make([]string, len(e.Warnings))
+	for i, w := range e.Warnings {
+		msgs[i] = w.Error()
+	}
+	return fmt.Sprintf("microdata: %d markup warning(s): %s", len(e.Warnings), strings.Join(msgs, "; "))
+}
+
+// WithStrict makes parsing fail with a *StrictError when it finds markup
+// anomalies, instead of recording them in the Warnings field of the microdata.
+func WithStrict() Option {
+	return func(p *parser) {
+		p.strict = true
+	}
+}
+
+// warn records a warning for the node. Repeated warnings for the same node,
+// e.g. for an element referenced by more than one item, are recorded once.
+func (p *parser) warn(code WarningCode, node *html.Node, format string, args ...interface{}) {
+	w := Warning{Code: code, Message: fmt.Sprintf(format, args...), Path: nodePath(node)}
+	if p.warned == nil {
+		p.warned = make(map[Warning]bool)
+	}
+	if p.warned[w] {
+		return
+	}
+	p.warned[w] = true
+	p.data.Warnings = append(p.data.Warnings, w)
+}
+
+// checkItemProp records a warning when the element with an itemprop attribute
+// isn't part of an item: it has no ancestor with an itemscope attribute, and
+// neither it nor an ancestor is referenced by an itemref attribute.
+func (p *parser) checkItemProp(node *html.Node, referenced map[string]bool) {
+	for n := node; n != nil; n = n.Parent {
+		if n != node {
+			if _, ok := getAttr("itemscope", n); ok {
+				return
+			}
+		}
+		if id, ok := getAttr("id", n); ok && referenced[id] {
+			return
+		}
+	}
+	p.warn(ItemPropOutsideItem, node, "itemprop %q is not part of an item", strings.TrimSpace(attrValue("itemprop", node)))
+}
+
+// attrValue returns the value of the attribute of the node, or "".
+func attrValue(attribute string, node *html.Node) string {
+	v, _ := getAttr(attribute, node)
+	return v
+}
+
+// nodePath returns the path of the element in the document, e.g.
+// "/html/body/div[2]/span". Elements with siblings of the same name have
+// their 1-based position among them.
+func nodePath(node *html.Node) string {
+	var parts []string
+	for n := node; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		part := n.Data
+		position, count := 0, 0
+		if n.Parent != nil {
+			for c := n.Parent.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && c.Data == n.Data {
+					count++
+					if c == n {
+						position = count
+					}
+				}
+			}
+		}
+		if count > 1 {
+			part = fmt.Sprintf("%s[%d]", part, position)
+		}
+		parts = append(parts, part)
+	}
+
+	var b strings.Builder
+	for i := len(parts) - 1; i >= 0; i-- {
+		b.WriteByte('/')
+		b.WriteString(parts[i])
+	}
+	return b.String()
+}
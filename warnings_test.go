@@ -0,0 +1,67 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var warningsSnippet = `<html><body>
+<div itemscope itemtype="http://schema.org/Product" itemid="%zz" itemref="brand missing">
+	<span itemprop="">Nameless</span>
+	<span itemprop="name">Anvil</span>
+</div>
+<div itemscope itemid="urn:sku:1" itemref="missing"></div>
+<p id="brand"><span itemprop="brand">ACME</span></p>
+<span itemprop="price">9.99</span>
+<div itemprop="offers" itemscope itemtype="http://schema.org/Offer"><span itemprop="price">9.99</span></div>
+</body></html>`
+
+func TestWarnings(t *testing.T) {
+	u, _ := url.Parse("http://example.com/")
+	data, err := ParseHTML(strings.NewReader(warningsSnippet), "text/html", u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Warning{
+		{ItemPropOutsideItem, `itemprop "price" is not part of an item`, "/html/body/span"},
+		{ItemPropOutsideItem, `itemprop "offers" is not part of an item`, "/html/body/div[3]"},
+		{InvalidItemID, `itemid "%zz" is not a valid URL`, "/html/body/div[1]"},
+		{MissingItemRef, `itemref "missing" references no element`, "/html/body/div[1]"},
+		{EmptyItemProp, "itemprop has no property names", "/html/body/div[1]/span[1]"},
+		{ItemIDWithoutType, "itemid on an item without itemtype", "/html/body/div[2]"},
+		{MissingItemRef, `itemref "missing" references no element`, "/html/body/div[2]"},
+	}
+	if !reflect.DeepEqual(data.Warnings, expected) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", expected, data.Warnings)
+	}
+
+	if len(data.Items) != 2 || data.Items[0].Properties["brand"][0] != "ACME" {
+		t.Errorf("Result should have been 2 items with a brand, but it was %v", data.Items)
+	}
+}
+
+func TestWithStrict(t *testing.T) {
+	u, _ := url.Parse("http://example.com/")
+	_, err := ParseHTML(strings.NewReader(warningsSnippet), "text/html", u, WithStrict())
+	strictErr, ok := err.(*StrictError)
+	if !ok {
+		t.Fatalf("Result should have been a *StrictError, but it was \"%v\"", err)
+	}
+	if len(strictErr.Warnings) != 7 {
+		t.Errorf("Result should have been 7 warnings, but it was %d", len(strictErr.Warnings))
+	}
+
+	data, err := ParseHTML(strings.NewReader(blogSnippet), "text/html", u, WithStrict())
+	if err != nil {
+		t.Errorf("Result should have been nil, but it was \"%v\"", err)
+	}
+	if data == nil || len(data.Warnings) != 0 {
+		t.Errorf("Result should have been no warnings, but it was %v", data)
+	}
+}